@@ -0,0 +1,360 @@
+package ast
+
+import (
+	"bytes"
+	"ciri/src/token"
+	"strings"
+)
+
+// Node is implemented by every node of the tree
+type Node interface {
+	// Pos returns the token the node starts at
+	Pos() token.Token
+	String() string
+}
+
+type Statement interface {
+	Node
+	statementNode()
+}
+
+type Expression interface {
+	Node
+	expressionNode()
+}
+
+// Program is the root of every ciri source file
+type Program struct {
+	Token token.Token
+	Name  *Ident
+	Vars  []*VarDecl
+	Funcs []*Function
+	Body  *Block
+}
+
+func (p *Program) Pos() token.Token { return p.Token }
+func (p *Program) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("program " + p.Name.String() + ":")
+	writeVars(&out, p.Vars)
+	for _, f := range p.Funcs {
+		out.WriteString(" " + f.String())
+	}
+	out.WriteString(" " + p.Body.String())
+
+	return out.String()
+}
+
+// VarDecl declares one or more variables sharing a type, e.g.
+// `x, grid[4][4]: int;`, `x: float = 2;` or `var x = 10;`. Dims[i] holds
+// the dimensions of Names[i], it is empty for scalars. Type is the zero
+// token when it is inferred from Value.
+type VarDecl struct {
+	Token token.Token
+	Names []*Ident
+	Dims  [][]Expression
+	Type  token.Token
+	Value Expression
+}
+
+func (v *VarDecl) Pos() token.Token { return v.Token }
+func (v *VarDecl) String() string {
+	names := make([]string, 0, len(v.Names))
+	for i, n := range v.Names {
+		name := n.String()
+		if i < len(v.Dims) {
+			name += brackets(v.Dims[i])
+		}
+		names = append(names, name)
+	}
+	out := strings.Join(names, ", ")
+	if v.Type.Type != "" {
+		out += ": " + v.Type.Literal
+	}
+	if v.Value != nil {
+		out += " = " + v.Value.String()
+	}
+	return out + ";"
+}
+
+// writeVars writes a declaration section, declarations without a type need
+// a var of their own
+func writeVars(out *bytes.Buffer, vars []*VarDecl) {
+	for i, v := range vars {
+		if i == 0 || v.Type.Type == "" {
+			out.WriteString(" var")
+		}
+		out.WriteString(" " + v.String())
+	}
+}
+
+// Function declares `func name(a: int): float { ... }`, Result is the zero
+// token for procedures that return nothing
+type Function struct {
+	Token  token.Token
+	Name   *Ident
+	Params []*Param
+	Result token.Token
+	Vars   []*VarDecl
+	Body   *Block
+}
+
+func (f *Function) Pos() token.Token { return f.Token }
+func (f *Function) String() string {
+	var out bytes.Buffer
+
+	params := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	out.WriteString("func " + f.Name.String() + "(" + strings.Join(params, ", ") + ")")
+	if f.Result.Type != "" {
+		out.WriteString(": " + f.Result.Literal)
+	}
+
+	out.WriteString(" {")
+	writeVars(&out, f.Vars)
+	for _, s := range f.Body.Statements {
+		out.WriteString(" " + s.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type Param struct {
+	Name *Ident
+	Type token.Token
+}
+
+func (p *Param) Pos() token.Token { return p.Name.Token }
+func (p *Param) String() string   { return p.Name.String() + ": " + p.Type.Literal }
+
+// Statements
+
+type Block struct {
+	Token      token.Token
+	Statements []Statement
+}
+
+func (b *Block) Pos() token.Token { return b.Token }
+func (b *Block) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	for _, s := range b.Statements {
+		out.WriteString(" " + s.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// BadStmt is a placeholder for a statement with syntax errors
+type BadStmt struct {
+	Token token.Token
+}
+
+func (b *BadStmt) statementNode()   {}
+func (b *BadStmt) Pos() token.Token { return b.Token }
+func (b *BadStmt) String() string   { return "<bad statement>;" }
+
+// DeclStmt declares a variable inside a block, it is visible from the
+// declaration to the end of the block
+type DeclStmt struct {
+	Decl *VarDecl
+}
+
+func (d *DeclStmt) statementNode()   {}
+func (d *DeclStmt) Pos() token.Token { return d.Decl.Token }
+func (d *DeclStmt) String() string   { return "var " + d.Decl.String() }
+
+// Assign stores Value in Target, an *Ident or an *Index
+type Assign struct {
+	Token  token.Token
+	Target Expression
+	Value  Expression
+}
+
+func (a *Assign) statementNode()   {}
+func (a *Assign) Pos() token.Token { return a.Token }
+func (a *Assign) String() string {
+	return a.Target.String() + " = " + a.Value.String() + ";"
+}
+
+type If struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence *Block
+	Alternative *Block
+}
+
+func (i *If) statementNode()   {}
+func (i *If) Pos() token.Token { return i.Token }
+func (i *If) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("if (" + i.Condition.String() + ") " + i.Consequence.String())
+	if i.Alternative != nil {
+		out.WriteString(" else " + i.Alternative.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type While struct {
+	Token     token.Token
+	Condition Expression
+	Body      *Block
+}
+
+func (w *While) statementNode()   {}
+func (w *While) Pos() token.Token { return w.Token }
+func (w *While) String() string {
+	return "while (" + w.Condition.String() + ") " + w.Body.String() + ";"
+}
+
+// For counts Var from Start to End inclusive, Step is nil when omitted
+type For struct {
+	Token token.Token
+	Var   *Ident
+	Start Expression
+	End   Expression
+	Step  Expression
+	Body  *Block
+}
+
+func (f *For) statementNode()   {}
+func (f *For) Pos() token.Token { return f.Token }
+func (f *For) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for " + f.Var.String() + " = " + f.Start.String() + " to " + f.End.String())
+	if f.Step != nil {
+		out.WriteString(" step " + f.Step.String())
+	}
+	out.WriteString(" " + f.Body.String() + ";")
+
+	return out.String()
+}
+
+// Return leaves the current function, Value is nil in procedures
+type Return struct {
+	Token token.Token
+	Value Expression
+}
+
+func (r *Return) statementNode()   {}
+func (r *Return) Pos() token.Token { return r.Token }
+func (r *Return) String() string {
+	if r.Value == nil {
+		return "return;"
+	}
+	return "return " + r.Value.String() + ";"
+}
+
+// CallStmt is a call whose result, if any, is discarded
+type CallStmt struct {
+	Call *Call
+}
+
+func (c *CallStmt) statementNode()   {}
+func (c *CallStmt) Pos() token.Token { return c.Call.Pos() }
+func (c *CallStmt) String() string   { return c.Call.String() + ";" }
+
+type Print struct {
+	Token token.Token
+	Args  []Expression
+}
+
+func (p *Print) statementNode()   {}
+func (p *Print) Pos() token.Token { return p.Token }
+func (p *Print) String() string {
+	args := make([]string, 0, len(p.Args))
+	for _, a := range p.Args {
+		args = append(args, a.String())
+	}
+	return "print(" + strings.Join(args, ", ") + ");"
+}
+
+// Expressions
+
+type BinaryExpr struct {
+	Token    token.Token
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+func (b *BinaryExpr) expressionNode()  {}
+func (b *BinaryExpr) Pos() token.Token { return b.Left.Pos() }
+func (b *BinaryExpr) String() string {
+	return "(" + b.Left.String() + " " + b.Operator + " " + b.Right.String() + ")"
+}
+
+type UnaryExpr struct {
+	Token    token.Token
+	Operator string
+	Operand  Expression
+}
+
+func (u *UnaryExpr) expressionNode()  {}
+func (u *UnaryExpr) Pos() token.Token { return u.Token }
+func (u *UnaryExpr) String() string {
+	return "(" + u.Operator + u.Operand.String() + ")"
+}
+
+type Call struct {
+	Token    token.Token
+	Function *Ident
+	Args     []Expression
+}
+
+func (c *Call) expressionNode()  {}
+func (c *Call) Pos() token.Token { return c.Token }
+func (c *Call) String() string {
+	args := make([]string, 0, len(c.Args))
+	for _, a := range c.Args {
+		args = append(args, a.String())
+	}
+	return c.Function.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Index is an element of an array, e.g. `grid[i][j]`
+type Index struct {
+	Token   token.Token
+	Array   *Ident
+	Indices []Expression
+}
+
+func (i *Index) expressionNode()  {}
+func (i *Index) Pos() token.Token { return i.Token }
+func (i *Index) String() string   { return i.Array.String() + brackets(i.Indices) }
+
+func brackets(exprs []Expression) string {
+	var out bytes.Buffer
+	for _, e := range exprs {
+		out.WriteString("[" + e.String() + "]")
+	}
+	return out.String()
+}
+
+// Literal is an int, float or string constant, its kind is the token type
+type Literal struct {
+	Token token.Token
+}
+
+func (l *Literal) expressionNode()  {}
+func (l *Literal) Pos() token.Token { return l.Token }
+func (l *Literal) String() string   { return l.Token.Literal }
+
+type Ident struct {
+	Token token.Token
+	Name  string
+}
+
+func (i *Ident) expressionNode()  {}
+func (i *Ident) Pos() token.Token { return i.Token }
+func (i *Ident) String() string   { return i.Name }
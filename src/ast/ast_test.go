@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"ciri/src/token"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	x := &Ident{Token: token.Token{Type: token.ID, Literal: "x"}, Name: "x"}
+	program := &Program{
+		Token: token.Token{Type: token.PROGRAM, Literal: "program"},
+		Name:  &Ident{Token: token.Token{Type: token.ID, Literal: "demo"}, Name: "demo"},
+		Vars: []*VarDecl{
+			{Names: []*Ident{x}, Type: token.Token{Type: token.INT_TYPE, Literal: "int"}},
+		},
+		Body: &Block{
+			Statements: []Statement{
+				&Assign{
+					Target: x,
+					Value: &BinaryExpr{
+						Operator: "*",
+						Left:     &Literal{Token: token.Token{Type: token.INT, Literal: "2"}},
+						Right: &UnaryExpr{
+							Operator: "-",
+							Operand:  x,
+						},
+					},
+				},
+				&Print{Args: []Expression{&Literal{Token: token.Token{Type: token.STRING, Literal: `"x"`}}, x}},
+			},
+		},
+	}
+
+	expected := `program demo: var x: int; { x = (2 * (-x)); print("x", x); }`
+	if program.String() != expected {
+		t.Fatalf("program.String() wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+}
+
+func TestArrayString(t *testing.T) {
+	i := &Ident{Token: token.Token{Type: token.ID, Literal: "i"}, Name: "i"}
+	grid := &Ident{Token: token.Token{Type: token.ID, Literal: "grid"}, Name: "grid"}
+	four := &Literal{Token: token.Token{Type: token.INT, Literal: "4"}}
+
+	decl := &VarDecl{
+		Names: []*Ident{i, grid},
+		Dims:  [][]Expression{nil, {four, four}},
+		Type:  token.Token{Type: token.INT_TYPE, Literal: "int"},
+	}
+	if decl.String() != "i, grid[4][4]: int;" {
+		t.Fatalf("decl.String() wrong, got=%q", decl.String())
+	}
+
+	assign := &Assign{Target: &Index{Array: grid, Indices: []Expression{i, four}}, Value: i}
+	if assign.String() != "grid[i][4] = i;" {
+		t.Fatalf("assign.String() wrong, got=%q", assign.String())
+	}
+}
+
+func TestInitializedVarString(t *testing.T) {
+	x := &Ident{Token: token.Token{Type: token.ID, Literal: "x"}, Name: "x"}
+	y := &Ident{Token: token.Token{Type: token.ID, Literal: "y"}, Name: "y"}
+	ten := &Literal{Token: token.Token{Type: token.INT, Literal: "10"}}
+
+	program := &Program{
+		Name: &Ident{Token: token.Token{Type: token.ID, Literal: "p"}, Name: "p"},
+		Vars: []*VarDecl{
+			{Names: []*Ident{x}, Dims: [][]Expression{nil}, Value: ten},
+			{Names: []*Ident{y}, Dims: [][]Expression{nil}, Type: token.Token{Type: token.FLOAT_TYPE, Literal: "float"}, Value: x},
+			{Names: []*Ident{x}, Dims: [][]Expression{nil}, Value: y},
+		},
+		Body: &Block{},
+	}
+	expected := "program p: var x = 10; y: float = x; var x = y; { }"
+	if program.String() != expected {
+		t.Fatalf("program.String() wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+}
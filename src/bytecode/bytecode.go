@@ -0,0 +1,47 @@
+// Package bytecode stores compiled ciri programs in a compact binary file so
+// they can be built on a workstation and shipped to devices.
+//
+// A file is laid out as
+//
+//	magic    "CIRI"
+//	version  uint16
+//	flags    uint16
+//	length   uint32  payload length in bytes
+//	payload
+//	checksum uint32  CRC-32 (IEEE) of everything before it
+//
+// and the payload holds, in order, the program name, the memory sizes, the
+// constant pool, the symbol table, the function table, the instruction stream and, when the
+// Debug flag is set, the line table. Integers are varints, floats are 8 byte
+// little endian IEEE 754 values and strings are a length followed by bytes.
+package bytecode
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Version is the format version written by Encode, Decode rejects others
+const Version = 2
+
+var magic = []byte("CIRI")
+
+const headerSize = 12
+
+type Flags uint16
+
+const (
+	// Debug adds the source line of every instruction
+	Debug Flags = 1 << iota
+)
+
+var (
+	ErrBadMagic  = errors.New("bytecode: not a ciri bytecode file")
+	ErrTruncated = errors.New("bytecode: file is truncated")
+	ErrChecksum  = errors.New("bytecode: checksum mismatch, file is corrupt")
+)
+
+// IsBytecode reports whether data starts with the bytecode magic
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
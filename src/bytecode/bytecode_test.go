@@ -0,0 +1,211 @@
+package bytecode
+
+import (
+	"bytes"
+	"ciri/src/goyacc"
+	"ciri/src/ir"
+	"ciri/src/semantic"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const source = `
+	program test: var x, d : int; f: float; msg: string;
+	func half(n: float): float {
+		return n / 2;
+	}
+	{
+		x = 10;
+		x = 20 + 50 + d;
+		f = half(x / 3.5);
+
+		if (x < 10) {
+			print("hello");
+		};
+
+		msg = "x=" + x;
+		print("hello", x, x > 10, f, msg, len(msg));
+	}
+`
+
+func compile(t *testing.T, input string) *ir.Program {
+	program, err := goyacc.Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := semantic.Check(program)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	p, err := ir.Generate(program, info)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return p
+}
+
+func encode(t *testing.T, p *ir.Program, flags Flags) []byte {
+	var buf bytes.Buffer
+	if err := Encode(&buf, p, flags); err != nil {
+		t.Fatalf(err.Error())
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	p := compile(t, source)
+	p.Constants.Bools = []bool{true, false}
+
+	decoded, err := Decode(bytes.NewReader(encode(t, p, Debug)))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(p, decoded) {
+		t.Fatalf("decoded program differs.\nexpected=%s\ngot=%s", p, decoded)
+	}
+}
+
+func TestRoundTripWithoutDebug(t *testing.T) {
+	p := compile(t, source)
+	data := encode(t, p, 0)
+
+	if len(data) >= len(encode(t, p, Debug)) {
+		t.Fatalf("expected the debug table to be omitted")
+	}
+
+	decoded, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, q := range decoded.Quads {
+		if q.Line != 0 {
+			t.Fatalf("quads[%d] - expected no line, got=%d", i, q.Line)
+		}
+		q.Line = p.Quads[i].Line
+		if q != p.Quads[i] {
+			t.Fatalf("quads[%d] wrong. expected=%v, got=%v", i, p.Quads[i], q)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data := encode(t, compile(t, source), Debug)
+
+	corrupt := append([]byte{}, data...)
+	corrupt[headerSize+3] ^= 0xff
+
+	version := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(version[4:], Version+1)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"empty", nil, ErrTruncated.Error()},
+		{"magic", []byte("#!/bin/sh\n"), ErrBadMagic.Error()},
+		{"header", data[:8], ErrTruncated.Error()},
+		{"payload", data[:len(data)-10], ErrTruncated.Error()},
+		{"checksum", data[:len(data)-1], ErrTruncated.Error()},
+		{"trailing", append(append([]byte{}, data...), 0), "unexpected bytes"},
+		{"corrupt", corrupt, ErrChecksum.Error()},
+		{"version", version, fmt.Sprintf("unsupported version %d", Version+1)},
+	}
+
+	for _, tt := range tests {
+		_, err := DecodeBytes(tt.data)
+		if err == nil {
+			t.Fatalf("%s - expected an error", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("%s - error wrong. expected=%q, got=%q", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestDecodeInvalidProgram(t *testing.T) {
+	var (
+		gInt  = ir.NewAddr(ir.Global, semantic.Int, 0)
+		tInt  = ir.NewAddr(ir.Temp, semantic.Int, 0)
+		cInt  = ir.NewAddr(ir.Const, semantic.Int, 0)
+		cStr  = ir.NewAddr(ir.Const, semantic.String, 0)
+		none  = ir.NoAddr
+		quads = func(q ...ir.Quad) []ir.Quad { return append(q, ir.Quad{Op: ir.END}) }
+	)
+
+	tests := []struct {
+		name     string
+		quads    []ir.Quad
+		expected string
+	}{
+		{"no end", []ir.Quad{}, "does not end"},
+		{"op", quads(ir.Quad{Op: 200, Left: none, Right: none, Result: none}), "unknown operation"},
+		{"address", quads(ir.Quad{Op: ir.PRINT, Left: ir.NewAddr(ir.Global, semantic.Int, 3), Right: none, Result: none}), "outside"},
+		{"jump", quads(ir.Quad{Op: ir.GOTO, Left: none, Right: none, Result: 7}), "jump target"},
+		{"call", quads(ir.Quad{Op: ir.ERA, Left: none, Right: none, Result: 1}), "unknown function"},
+		{"return", quads(ir.Quad{Op: ir.ENDFUNC, Left: none, Right: none, Result: none}), "outside of a function"},
+		{"clear nothing", quads(ir.Quad{Op: ir.CLEAR, Left: none, Right: none, Result: 1}), "needs a variable"},
+		{"clear count", quads(ir.Quad{Op: ir.CLEAR, Left: gInt, Right: none, Result: 0}), "invalid count"},
+		{"clear span", quads(ir.Quad{Op: ir.CLEAR, Left: tInt, Right: none, Result: 2}), "outside"},
+		{"operand type", quads(ir.Quad{Op: ir.ADD, Left: cStr, Right: cStr, Result: tInt}), "is string, not int"},
+		{"missing operand", quads(ir.Quad{Op: ir.PRINT, Left: none, Right: none, Result: none}), "missing an operand"},
+		{"condition", quads(ir.Quad{Op: ir.GOTOF, Left: cInt, Right: none, Result: 0}), "is int, not bool"},
+		{"verify", quads(ir.Quad{Op: ir.VERIFY, Left: cStr, Right: none, Result: 2}), "is string, not int"},
+		{"step", quads(ir.Quad{Op: ir.CHECKSTEP, Left: cStr, Right: none, Result: none}), "is string, not int"},
+		{"load offset", quads(ir.Quad{Op: ir.LOAD, Left: gInt, Right: cStr, Result: tInt}), "is string, not int"},
+		{"store offset", quads(ir.Quad{Op: ir.STORE, Left: cInt, Right: cStr, Result: gInt}), "is string, not int"},
+		{"copy", quads(ir.Quad{Op: ir.ASSIGN, Left: cStr, Right: none, Result: gInt}), "cannot copy string"},
+		{"param", quads(
+			ir.Quad{Op: ir.ERA, Left: none, Right: none, Result: 0},
+			ir.Quad{Op: ir.PARAM, Left: cStr, Right: none, Result: 0},
+			ir.Quad{Op: ir.GOSUB, Left: none, Right: none, Result: 0},
+		), "cannot copy string"},
+		{"jump into call", quads(
+			ir.Quad{Op: ir.GOTO, Left: none, Right: none, Result: 3},
+			ir.Quad{Op: ir.ERA, Left: none, Right: none, Result: 0},
+			ir.Quad{Op: ir.PARAM, Left: cInt, Right: none, Result: 0},
+			ir.Quad{Op: ir.GOSUB, Left: none, Right: none, Result: 0},
+		), "inside a call"},
+	}
+
+	// the second function starts past the end of the quadruples
+	p := &ir.Program{
+		Quads: []ir.Quad{
+			{Op: ir.END, Left: none, Right: none, Result: none},
+			{Op: ir.ENDFUNC, Left: none, Right: none, Result: none},
+		},
+		Functions: []ir.Function{
+			{Name: "f", Start: 1, Result: none},
+			{Name: "g", Start: 9, Result: none},
+		},
+	}
+	if _, err := DecodeBytes(encode(t, p, 0)); err == nil || !strings.Contains(err.Error(), "function g: invalid start 9") {
+		t.Fatalf("function start - expected an invalid start, got=%v", err)
+	}
+
+	for _, tt := range tests {
+		// every program gets one slot of each kind and a function f(int)
+		// starting right after its main block
+		p := &ir.Program{
+			Quads:      append(tt.quads, ir.Quad{Op: ir.ENDFUNC, Left: none, Right: none, Result: none}),
+			Constants:  ir.Constants{Ints: []int64{1}, Strings: []string{"s"}},
+			GlobalSize: ir.Size{Ints: 1},
+			TempSize:   ir.Size{Ints: 1},
+			Functions: []ir.Function{{
+				Name:      "f",
+				Start:     len(tt.quads),
+				Params:    []ir.Addr{ir.NewAddr(ir.Local, semantic.Int, 0)},
+				Result:    none,
+				LocalSize: ir.Size{Ints: 1},
+			}},
+		}
+		_, err := DecodeBytes(encode(t, p, 0))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("%s - expected %q, got=%v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package bytecode
+
+import (
+	"ciri/src/ir"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+// Decode reads a program written by Encode, the header and checksum are
+// verified and the program is validated before it is returned
+func Decode(r io.Reader) (*ir.Program, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBytes(data)
+}
+
+func DecodeBytes(data []byte) (*ir.Program, error) {
+	if len(data) < len(magic) {
+		return nil, ErrTruncated
+	}
+	if !IsBytecode(data) {
+		return nil, ErrBadMagic
+	}
+	if len(data) < headerSize {
+		return nil, ErrTruncated
+	}
+
+	version := binary.LittleEndian.Uint16(data[4:])
+	if version != Version {
+		return nil, fmt.Errorf("bytecode: unsupported version %d, expected %d", version, Version)
+	}
+	flags := Flags(binary.LittleEndian.Uint16(data[6:]))
+
+	length := uint64(binary.LittleEndian.Uint32(data[8:]))
+	end := headerSize + length
+	if uint64(len(data)) < end+4 {
+		return nil, ErrTruncated
+	}
+	if uint64(len(data)) > end+4 {
+		return nil, fmt.Errorf("bytecode: %d unexpected bytes after the checksum", uint64(len(data))-end-4)
+	}
+	if crc32.ChecksumIEEE(data[:end]) != binary.LittleEndian.Uint32(data[end:]) {
+		return nil, ErrChecksum
+	}
+
+	d := &decoder{buf: data[headerSize:end]}
+	p := d.program(flags)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.buf) != 0 {
+		return nil, fmt.Errorf("bytecode: %d unexpected bytes after the program", len(d.buf))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("bytecode: invalid program: %s", err)
+	}
+	return p, nil
+}
+
+func (d *decoder) program(flags Flags) *ir.Program {
+	p := &ir.Program{}
+	p.Name = d.string()
+	p.GlobalSize = d.size()
+	p.TempSize = d.size()
+	p.Constants = d.constants()
+
+	p.Globals = make([]ir.Symbol, d.count(2))
+	for i := range p.Globals {
+		p.Globals[i].Name = d.string()
+		p.Globals[i].Addr = ir.Addr(d.varint())
+	}
+
+	// a function takes at least a byte per name length, start, parameter
+	// count and result plus four per memory size
+	if n := d.count(12); n > 0 {
+		p.Functions = make([]ir.Function, n)
+		for i := range p.Functions {
+			p.Functions[i] = d.function()
+		}
+	}
+
+	p.Quads = make([]ir.Quad, d.count(4))
+	for i := range p.Quads {
+		q := &p.Quads[i]
+		q.Op = ir.Op(d.byte())
+		q.Left = ir.Addr(d.varint())
+		q.Right = ir.Addr(d.varint())
+		q.Result = ir.Addr(d.varint())
+	}
+
+	if flags&Debug != 0 {
+		for i := range p.Quads {
+			p.Quads[i].Line = uint32(d.uvarint())
+		}
+	}
+
+	return p
+}
+
+func (d *decoder) function() ir.Function {
+	f := ir.Function{Name: d.string(), Start: int(d.uvarint())}
+	if n := d.count(1); n > 0 {
+		f.Params = make([]ir.Addr, n)
+		for i := range f.Params {
+			f.Params[i] = ir.Addr(d.varint())
+		}
+	}
+	f.Result = ir.Addr(d.varint())
+	f.LocalSize = d.size()
+	f.TempSize = d.size()
+	return f
+}
+
+func (d *decoder) size() ir.Size {
+	return ir.Size{
+		Ints:    d.slots(),
+		Floats:  d.slots(),
+		Bools:   d.slots(),
+		Strings: d.slots(),
+	}
+}
+
+func (d *decoder) constants() ir.Constants {
+	var c ir.Constants
+
+	c.Ints = make([]int64, d.count(1))
+	for i := range c.Ints {
+		c.Ints[i] = d.varint()
+	}
+	c.Floats = make([]float64, d.count(8))
+	for i := range c.Floats {
+		c.Floats[i] = math.Float64frombits(d.uint64())
+	}
+	c.Bools = make([]bool, d.count(1))
+	for i := range c.Bools {
+		c.Bools[i] = d.byte() != 0
+	}
+	c.Strings = make([]string, d.count(1))
+	for i := range c.Strings {
+		c.Strings[i] = d.string()
+	}
+
+	return c
+}
+
+// Primitives, once an error is found every read returns the zero value
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.buf = nil
+}
+
+// count reads a length prefix, each counted element takes at least
+// minSize bytes so lengths the remaining input cannot hold are rejected
+// before anything is allocated
+func (d *decoder) count(minSize int) int {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)/minSize) {
+		d.fail(fmt.Errorf("bytecode: length %d exceeds the file size", n))
+		return 0
+	}
+	return int(n)
+}
+
+// slots reads the size of a memory segment for one type
+func (d *decoder) slots() int {
+	n := d.uvarint()
+	if n > ir.TypeSpan {
+		d.fail(fmt.Errorf("bytecode: %d slots exceed the address space", n))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail(ErrTruncated)
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail(ErrTruncated)
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if len(d.buf) < 1 {
+		d.fail(ErrTruncated)
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uint64() uint64 {
+	if len(d.buf) < 8 {
+		d.fail(ErrTruncated)
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.count(1)
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
@@ -0,0 +1,125 @@
+package bytecode
+
+import (
+	"ciri/src/ir"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+type encoder struct {
+	buf     []byte
+	scratch [binary.MaxVarintLen64]byte
+}
+
+// Encode writes p to w
+func Encode(w io.Writer, p *ir.Program, flags Flags) error {
+	e := &encoder{buf: make([]byte, headerSize, 256)}
+	copy(e.buf, magic)
+	binary.LittleEndian.PutUint16(e.buf[4:], Version)
+	binary.LittleEndian.PutUint16(e.buf[6:], uint16(flags))
+
+	e.string(p.Name)
+	e.size(p.GlobalSize)
+	e.size(p.TempSize)
+	e.constants(p.Constants)
+
+	e.uvarint(uint64(len(p.Globals)))
+	for _, s := range p.Globals {
+		e.string(s.Name)
+		e.varint(int64(s.Addr))
+	}
+
+	e.uvarint(uint64(len(p.Functions)))
+	for _, f := range p.Functions {
+		e.function(f)
+	}
+
+	e.uvarint(uint64(len(p.Quads)))
+	for _, q := range p.Quads {
+		e.buf = append(e.buf, byte(q.Op))
+		e.varint(int64(q.Left))
+		e.varint(int64(q.Right))
+		e.varint(int64(q.Result))
+	}
+
+	if flags&Debug != 0 {
+		for _, q := range p.Quads {
+			e.uvarint(uint64(q.Line))
+		}
+	}
+
+	binary.LittleEndian.PutUint32(e.buf[8:], uint32(len(e.buf)-headerSize))
+	e.uint32(crc32.ChecksumIEEE(e.buf))
+
+	_, err := w.Write(e.buf)
+	return err
+}
+
+func (e *encoder) size(s ir.Size) {
+	e.uvarint(uint64(s.Ints))
+	e.uvarint(uint64(s.Floats))
+	e.uvarint(uint64(s.Bools))
+	e.uvarint(uint64(s.Strings))
+}
+
+func (e *encoder) function(f ir.Function) {
+	e.string(f.Name)
+	e.uvarint(uint64(f.Start))
+	e.uvarint(uint64(len(f.Params)))
+	for _, a := range f.Params {
+		e.varint(int64(a))
+	}
+	e.varint(int64(f.Result))
+	e.size(f.LocalSize)
+	e.size(f.TempSize)
+}
+
+func (e *encoder) constants(c ir.Constants) {
+	e.uvarint(uint64(len(c.Ints)))
+	for _, v := range c.Ints {
+		e.varint(v)
+	}
+	e.uvarint(uint64(len(c.Floats)))
+	for _, v := range c.Floats {
+		e.uint64(math.Float64bits(v))
+	}
+	e.uvarint(uint64(len(c.Bools)))
+	for _, v := range c.Bools {
+		if v {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	}
+	e.uvarint(uint64(len(c.Strings)))
+	for _, v := range c.Strings {
+		e.string(v)
+	}
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *encoder) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:4]...)
+}
+
+func (e *encoder) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:8]...)
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
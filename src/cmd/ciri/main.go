@@ -0,0 +1,231 @@
+// Command ciri runs and inspects ciri programs.
+//
+//	ciri run file.ld                  compile and execute a program
+//	ciri run prog.cbc                 execute a compiled program
+//	ciri build [-o prog.cbc] file.ld  compile a program to bytecode
+//	ciri check file.ld                report syntax and semantic errors
+//	ciri tokens file.ld               dump the token stream
+//	ciri ast file.ld                  dump the syntax tree
+package main
+
+import (
+	"bytes"
+	"ciri/src/ast"
+	"ciri/src/bytecode"
+	"ciri/src/diag"
+	"ciri/src/goyacc"
+	"ciri/src/ir"
+	"ciri/src/semantic"
+	"ciri/src/token"
+	"ciri/src/vm"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `usage: ciri <command> [flags] file
+
+commands:
+  run     compile and execute a program, or execute a .cbc file
+  build   compile a program to bytecode
+  check   report syntax and semantic errors
+  tokens  dump the token stream
+  ast     dump the syntax tree
+`
+
+type options struct {
+	input  string
+	output string
+	strip  bool
+	// stderr gets the warnings of programs that compile
+	stderr io.Writer
+}
+
+type command struct {
+	run func(ctx context.Context, data []byte, opts *options, stdout io.Writer) error
+	// flags registers the flags of the command
+	flags func(fs *flag.FlagSet, opts *options)
+}
+
+var commands = map[string]command{
+	"run":    {run: runCommand},
+	"build":  {run: buildCommand, flags: buildFlags},
+	"check":  {run: checkCommand},
+	"tokens": {run: tokensCommand},
+	"ast":    {run: astCommand},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "ciri: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	opts := &options{stderr: stderr}
+	fs := flag.NewFlagSet("ciri "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if cmd.flags != nil {
+		cmd.flags(fs, opts)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	opts.input = fs.Arg(0)
+
+	data, err := os.ReadFile(opts.input)
+	if err != nil {
+		fmt.Fprintf(stderr, "ciri: %s\n", err)
+		return 1
+	}
+
+	if err := cmd.run(ctx, data, opts, stdout); err != nil {
+		report(stderr, opts.input, data, err)
+		return 1
+	}
+	return 0
+}
+
+// report prints err, diagnostics are rendered with their source line
+func report(stderr io.Writer, path string, source []byte, err error) {
+	var diagnostics diag.DiagnosticList
+	if !errors.As(err, &diagnostics) {
+		fmt.Fprintf(stderr, "%s: %s\n", path, err)
+		return
+	}
+
+	render(stderr, path, source, diagnostics)
+}
+
+// render prints diagnostics in source order with their source line
+func render(w io.Writer, path string, source []byte, diagnostics diag.DiagnosticList) {
+	diagnostics.Sort()
+	r := &diag.Renderer{Filename: path, Source: string(source), Color: isTerminal(w)}
+	r.Render(w, diagnostics)
+}
+
+// isTerminal reports whether w is a terminal that should get colors
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Commands
+
+func runCommand(ctx context.Context, data []byte, opts *options, stdout io.Writer) error {
+	var program *ir.Program
+	var err error
+
+	if bytecode.IsBytecode(data) {
+		program, err = bytecode.DecodeBytes(data)
+	} else {
+		program, err = compile(data, opts)
+	}
+	if err != nil {
+		return err
+	}
+	return vm.Run(ctx, program, stdout)
+}
+
+func buildFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.output, "o", "", "output `file`, defaults to the input with a .cbc extension")
+	fs.BoolVar(&opts.strip, "strip", false, "omit the debug line table")
+}
+
+func buildCommand(ctx context.Context, data []byte, opts *options, stdout io.Writer) error {
+	program, err := compile(data, opts)
+	if err != nil {
+		return err
+	}
+
+	var flags bytecode.Flags
+	if !opts.strip {
+		flags |= bytecode.Debug
+	}
+
+	var out bytes.Buffer
+	if err := bytecode.Encode(&out, program, flags); err != nil {
+		return err
+	}
+
+	output := opts.output
+	if output == "" {
+		output = strings.TrimSuffix(opts.input, filepath.Ext(opts.input)) + ".cbc"
+	}
+	return os.WriteFile(output, out.Bytes(), 0644)
+}
+
+func checkCommand(ctx context.Context, data []byte, opts *options, stdout io.Writer) error {
+	_, _, err := check(data, opts)
+	return err
+}
+
+func tokensCommand(ctx context.Context, data []byte, opts *options, stdout io.Writer) error {
+	l := goyacc.New(string(data))
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(stdout, "%d:%d\t%s\t%s\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+	return nil
+}
+
+func astCommand(ctx context.Context, data []byte, opts *options, stdout io.Writer) error {
+	program, err := goyacc.Parse(string(data))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, program)
+	return nil
+}
+
+// Pipeline
+
+// check parses and checks source, warnings are printed without failing
+func check(source []byte, opts *options) (*ast.Program, *semantic.Info, error) {
+	program, err := goyacc.Parse(string(source))
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := semantic.Check(program)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(info.Warnings) > 0 {
+		render(opts.stderr, opts.input, source, info.Warnings)
+	}
+	return program, info, nil
+}
+
+func compile(source []byte, opts *options) (*ir.Program, error) {
+	program, info, err := check(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ir.Generate(program, info)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSource(t *testing.T, source string) string {
+	path := filepath.Join(t.TempDir(), "test.ld")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	return path
+}
+
+func execute(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+const valid = `program test: var x, d : int;  {
+    x = 10;
+    x = 20 + 50 + d;
+
+    if (x < 10) {
+        print("hello");
+    };
+
+    print("hello", x, x > 10);
+}`
+
+const undeclared = `program test: var x, d : int;  {
+    x = 10;
+    x = 20 + 50 + ty;
+}`
+
+const shadowed = `program test: var x: int; {
+    x = 1;
+    if (x > 0) {
+        var x = "inner";
+        print(x);
+    };
+}`
+
+func TestRunCommand(t *testing.T) {
+	code, stdout, stderr := execute("run", writeSource(t, valid))
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d\n%s", code, stderr)
+	}
+	if stdout != "hello 70 true\n" {
+		t.Fatalf("output wrong. got=%q", stdout)
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	code, _, stderr := execute("check", writeSource(t, valid))
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d\n%s", code, stderr)
+	}
+
+	code, _, stderr = execute("check", writeSource(t, undeclared))
+	if code != 1 {
+		t.Fatalf("exit code wrong. expected=1, got=%d", code)
+	}
+	if !strings.Contains(stderr, "undeclared identifier ty") {
+		t.Fatalf("expected undeclared error, got=%q", stderr)
+	}
+
+	// the checker reports the value before the target, output follows the source
+	code, _, stderr = execute("check", writeSource(t, "program test: {\n    b[0] = x + y;\n}"))
+	if code != 1 {
+		t.Fatalf("exit code wrong. expected=1, got=%d", code)
+	}
+	b, x, y := strings.Index(stderr, "2:5:"), strings.Index(stderr, "2:12:"), strings.Index(stderr, "2:16:")
+	if b < 0 || x < b || y < x {
+		t.Fatalf("errors should be in source order, got=%q", stderr)
+	}
+
+	for _, cmd := range []string{"check", "run"} {
+		code, _, stderr = execute(cmd, writeSource(t, shadowed))
+		if code != 0 {
+			t.Fatalf("%s - warnings should not fail, got=%d\n%s", cmd, code, stderr)
+		}
+		if !strings.Contains(stderr, "warning") || !strings.Contains(stderr, "declaration of x shadows an outer variable") {
+			t.Fatalf("%s - expected a shadowing warning, got=%q", cmd, stderr)
+		}
+	}
+}
+
+func TestTokensCommand(t *testing.T) {
+	code, stdout, _ := execute("tokens", writeSource(t, "program p:\n{ }"))
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d", code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 tokens, got=%d\n%s", len(lines), stdout)
+	}
+	if lines[3] != "2:1\t{\t{" {
+		t.Fatalf("token line wrong. got=%q", lines[3])
+	}
+}
+
+func TestAstCommand(t *testing.T) {
+	code, stdout, _ := execute("ast", writeSource(t, "program p: var x: int; { x = 1 + 2; }"))
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d", code)
+	}
+	if stdout != "program p: var x: int; { x = (1 + 2); }\n" {
+		t.Fatalf("ast wrong. got=%q", stdout)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, _ := execute("run"); code != 2 {
+		t.Fatalf("exit code wrong. expected=2, got=%d", code)
+	}
+	if code, _, stderr := execute("fly", "x.ld"); code != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Fatalf("expected unknown command, got=%d %q", code, stderr)
+	}
+	if code, _, _ := execute("run", "missing.ld"); code != 1 {
+		t.Fatalf("exit code wrong. expected=1, got=%d", code)
+	}
+}
+
+func TestBuildAndRunBytecode(t *testing.T) {
+	path := writeSource(t, valid)
+	output := filepath.Join(filepath.Dir(path), "prog.cbc")
+
+	code, _, stderr := execute("build", "-o", output, path)
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d\n%s", code, stderr)
+	}
+
+	code, stdout, stderr := execute("run", output)
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d\n%s", code, stderr)
+	}
+	if stdout != "hello 70 true\n" {
+		t.Fatalf("output wrong. got=%q", stdout)
+	}
+}
+
+func TestBuildDefaultOutput(t *testing.T) {
+	path := writeSource(t, valid)
+
+	if code, _, stderr := execute("build", "-strip", path); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d\n%s", code, stderr)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(path, ".ld") + ".cbc"); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestRunCorruptBytecode(t *testing.T) {
+	path := writeSource(t, valid)
+	output := filepath.Join(filepath.Dir(path), "prog.cbc")
+	execute("build", "-o", output, path)
+
+	data, _ := os.ReadFile(output)
+	os.WriteFile(output, data[:len(data)-3], 0644)
+
+	code, _, stderr := execute("run", output)
+	if code != 1 || !strings.Contains(stderr, "truncated") {
+		t.Fatalf("expected a truncated error, got=%d %q", code, stderr)
+	}
+}
@@ -0,0 +1,182 @@
+// Package diag holds the diagnostics reported by the lexer, the parser and
+// the checker so tools can inspect, sort, filter and render them.
+package diag
+
+import (
+	"ciri/src/token"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Code identifies the kind of a diagnostic
+type Code string
+
+const (
+	IllegalToken  Code = "illegal-token"
+	InvalidEscape Code = "invalid-escape"
+	OpenComment   Code = "unterminated-comment"
+	OpenString    Code = "unterminated-string"
+	Syntax        Code = "syntax"
+	Undeclared    Code = "undeclared"
+	Redeclared    Code = "redeclared"
+	TypeMismatch  Code = "type-mismatch"
+	InvalidConst  Code = "invalid-constant"
+	InvalidStep   Code = "invalid-step"
+	InvalidCall   Code = "invalid-call"
+	InvalidReturn Code = "invalid-return"
+	MissingReturn Code = "missing-return"
+	InvalidIndex  Code = "invalid-index"
+	OutOfMemory   Code = "out-of-memory"
+	Shadowed      Code = "shadowed"
+)
+
+type Pos struct {
+	// Offset is the byte offset in the source
+	Offset int
+	// Line is 1-based
+	Line uint32
+	// Column is 1-based, 0 when it is not known
+	Column uint32
+}
+
+func (p Pos) String() string {
+	if p.Column == 0 {
+		return fmt.Sprintf("%d", p.Line)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+func (p Pos) Before(other Pos) bool {
+	if p.Line != other.Line {
+		return p.Line < other.Line
+	}
+	return p.Offset < other.Offset
+}
+
+// PosOf returns the position a token starts at
+func PosOf(tok token.Token) Pos {
+	return Pos{Offset: tok.Offset, Line: tok.Line, Column: tok.Column}
+}
+
+// EndOf returns the position right after a token
+func EndOf(tok token.Token) Pos {
+	return Pos{
+		Offset: tok.EndOffset,
+		Line:   tok.Line,
+		Column: tok.Column + uint32(utf8.RuneCountInString(tok.Literal)),
+	}
+}
+
+// Note adds context to a diagnostic, e.g. where something was declared
+type Note struct {
+	Pos     Pos
+	Message string
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Pos      Pos
+	EndPos   Pos
+	Code     Code
+	Message  string
+	Notes    []Note
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// Errorf builds an error diagnostic spanning tok
+func Errorf(code Code, tok token.Token, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: Error,
+		Pos:      PosOf(tok),
+		EndPos:   EndOf(tok),
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Warningf builds a warning diagnostic spanning tok
+func Warningf(code Code, tok token.Token, format string, args ...interface{}) *Diagnostic {
+	d := Errorf(code, tok, format, args...)
+	d.Severity = Warning
+	return d
+}
+
+// DiagnosticList is a list of diagnostics, it is used as the error of every
+// compilation stage
+type DiagnosticList []*Diagnostic
+
+func (l *DiagnosticList) Add(d *Diagnostic) {
+	*l = append(*l, d)
+}
+
+func (l DiagnosticList) Len() int           { return len(l) }
+func (l DiagnosticList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l DiagnosticList) Less(i, j int) bool { return l[i].Pos.Before(l[j].Pos) }
+
+// Sort orders the list by position, keeping the report order of
+// diagnostics at the same position
+func (l DiagnosticList) Sort() {
+	sort.Stable(l)
+}
+
+// Filter returns the diagnostics with the given code
+func (l DiagnosticList) Filter(code Code) DiagnosticList {
+	var filtered DiagnosticList
+	for _, d := range l {
+		if d.Code == code {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// HasErrors reports whether any diagnostic has Error severity
+func (l DiagnosticList) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (l DiagnosticList) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, d := range l {
+		messages = append(messages, d.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error if it holds any error, warnings alone
+// do not fail a compilation
+func (l DiagnosticList) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,60 @@
+package diag
+
+import (
+	"ciri/src/token"
+	"testing"
+)
+
+func TestErrorf(t *testing.T) {
+	tok := token.Token{Type: token.ID, Literal: "speed", Offset: 30, EndOffset: 35, Line: 5, Column: 10}
+	d := Errorf(Undeclared, tok, "undeclared identifier %s", tok.Literal)
+
+	if d.Pos != (Pos{Offset: 30, Line: 5, Column: 10}) || d.EndPos != (Pos{Offset: 35, Line: 5, Column: 15}) {
+		t.Fatalf("span wrong, got=%v-%v", d.Pos, d.EndPos)
+	}
+	if d.Error() != "5:10: error: undeclared identifier speed" {
+		t.Fatalf("message wrong, got=%q", d.Error())
+	}
+}
+
+func TestSortAndFilter(t *testing.T) {
+	list := DiagnosticList{
+		{Pos: Pos{Offset: 20, Line: 3}, Code: Syntax, Message: "c"},
+		{Pos: Pos{Offset: 8, Line: 1}, Code: Undeclared, Message: "b"},
+		{Pos: Pos{Offset: 1, Line: 1}, Code: Syntax, Message: "a"},
+		{Pos: Pos{Offset: 8, Line: 1}, Code: Syntax, Message: "b2"},
+	}
+	list.Sort()
+
+	expected := []string{"a", "b", "b2", "c"}
+	for i, d := range list {
+		if d.Message != expected[i] {
+			t.Fatalf("list[%d] wrong. expected=%q, got=%q", i, expected[i], d.Message)
+		}
+	}
+
+	syntax := list.Filter(Syntax)
+	if len(syntax) != 3 {
+		t.Fatalf("expected 3 syntax diagnostics, got=%d", len(syntax))
+	}
+}
+
+func TestErr(t *testing.T) {
+	var list DiagnosticList
+	if list.Err() != nil {
+		t.Fatalf("empty list should not be an error")
+	}
+
+	list.Add(&Diagnostic{Severity: Warning, Pos: Pos{Line: 1, Column: 4}, Message: "unused"})
+	if list.Err() != nil {
+		t.Fatalf("warnings should not be an error")
+	}
+
+	list.Add(&Diagnostic{Severity: Error, Pos: Pos{Line: 2}, Message: "broken"})
+	if list.Err() == nil {
+		t.Fatalf("expected an error")
+	}
+	if list.Error() != "1:4: warning: unused\n2: error: broken" {
+		t.Fatalf("message wrong, got=%q", list.Error())
+	}
+}
@@ -0,0 +1,157 @@
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	cyan   = "\x1b[36m"
+)
+
+// Renderer prints diagnostics as
+//
+//	file.ld:5:12: error: unexpected '='
+//	    x = = 2;
+//	        ^
+//
+// underlining the span of the diagnostic in its source line
+type Renderer struct {
+	Filename string
+	Source   string
+	// Color enables ANSI colors
+	Color bool
+}
+
+// Render writes every diagnostic of list
+func (r *Renderer) Render(w io.Writer, list DiagnosticList) error {
+	var out bytes.Buffer
+	for _, d := range list {
+		r.diagnostic(&out, d)
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (r *Renderer) diagnostic(out *bytes.Buffer, d *Diagnostic) {
+	pos, line := r.locate(d.Pos)
+
+	r.header(out, pos, d.Severity.String(), severityColor(d.Severity), d.Message)
+	if pos.Column > 0 {
+		r.snippet(out, line, int(pos.Column)-1, r.width(d))
+	}
+
+	for _, note := range d.Notes {
+		pos, _ := r.locate(note.Pos)
+		r.header(out, pos, "note", cyan, note.Message)
+	}
+}
+
+// locate returns the source line of pos, filling in the column from the
+// offset when it is not known
+func (r *Renderer) locate(pos Pos) (Pos, string) {
+	line, start := r.line(pos)
+	if start < 0 {
+		return pos, ""
+	}
+	if pos.Column == 0 && pos.Offset >= start && pos.Offset <= start+len(line) {
+		pos.Column = uint32(utf8.RuneCountInString(line[:pos.Offset-start])) + 1
+	}
+	return pos, line
+}
+
+// width returns the number of characters d spans, columns count
+// characters too
+func (r *Renderer) width(d *Diagnostic) int {
+	start, end := d.Pos.Offset, d.EndPos.Offset
+	if start < 0 || start > end || end > len(r.Source) {
+		return end - start
+	}
+	return utf8.RuneCountInString(r.Source[start:end])
+}
+
+func (r *Renderer) header(out *bytes.Buffer, pos Pos, severity, color, message string) {
+	location := pos.String()
+	if r.Filename != "" {
+		location = r.Filename + ":" + location
+	}
+
+	if r.Color {
+		fmt.Fprintf(out, "%s%s:%s %s%s:%s %s%s%s\n", bold, location, reset, color+bold, severity, reset, bold, message, reset)
+		return
+	}
+	fmt.Fprintf(out, "%s: %s: %s\n", location, severity, message)
+}
+
+// snippet prints the source line and a caret under column spanning width
+// characters, tabs are kept so the caret lines up with the source
+func (r *Renderer) snippet(out *bytes.Buffer, line string, column, width int) {
+	chars := []rune(line)
+	if column > len(chars) {
+		column = len(chars)
+	}
+	if width < 1 {
+		width = 1
+	}
+	if column+width > len(chars) && column < len(chars) {
+		width = len(chars) - column
+	}
+
+	var indent strings.Builder
+	for _, c := range chars[:column] {
+		if c == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+
+	underline := "^" + strings.Repeat("~", width-1)
+	if r.Color {
+		underline = green + bold + underline + reset
+	}
+
+	fmt.Fprintf(out, "%s\n%s%s\n", line, indent.String(), underline)
+}
+
+// line returns the source line of pos and the offset it starts at, start
+// is -1 when the line is not in the source
+func (r *Renderer) line(pos Pos) (string, int) {
+	if pos.Line == 0 {
+		return "", -1
+	}
+
+	start := 0
+	for n := uint32(1); n < pos.Line; n++ {
+		i := strings.IndexByte(r.Source[start:], '\n')
+		if i < 0 {
+			return "", -1
+		}
+		start += i + 1
+	}
+
+	line := r.Source[start:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return strings.TrimSuffix(line, "\r"), start
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case Error:
+		return red
+	case Warning:
+		return yellow
+	default:
+		return cyan
+	}
+}
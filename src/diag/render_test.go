@@ -0,0 +1,86 @@
+package diag
+
+import (
+	"bytes"
+	"ciri/src/token"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "program p: {\n\tx = = 2;\n\tspeed = 1;\n}"
+	list := DiagnosticList{
+		Errorf(Syntax, token.Token{Literal: "=", Offset: 18, EndOffset: 19, Line: 2, Column: 6}, "unexpected '='"),
+		Errorf(Undeclared, token.Token{Literal: "speed", Offset: 24, EndOffset: 29, Line: 3, Column: 2}, "undeclared identifier speed"),
+	}
+	list[1].Notes = []Note{{Pos: Pos{Offset: 0, Line: 1}, Message: "in program p"}}
+
+	var out bytes.Buffer
+	r := &Renderer{Filename: "test.ld", Source: source}
+	if err := r.Render(&out, list); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := strings.Join([]string{
+		"test.ld:2:6: error: unexpected '='",
+		"\tx = = 2;",
+		"\t    ^",
+		"test.ld:3:2: error: undeclared identifier speed",
+		"\tspeed = 1;",
+		"\t^~~~~",
+		"test.ld:1:1: note: in program p",
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Fatalf("render wrong.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestRenderUnicode(t *testing.T) {
+	source := "program p: {\n\tö = \"héllo wörld\";\n}"
+	literal := `"héllo wörld"`
+	offset := strings.Index(source, literal)
+
+	var out bytes.Buffer
+	r := &Renderer{Source: source}
+	r.Render(&out, DiagnosticList{
+		Errorf(TypeMismatch, token.Token{Literal: literal, Offset: offset, EndOffset: offset + len(literal), Line: 2, Column: 6}, "mismatch"),
+		{Severity: Warning, Pos: Pos{Offset: offset, Line: 2}, EndPos: Pos{Offset: offset + 1}, Message: "no column"},
+	})
+
+	expected := strings.Join([]string{
+		"2:6: error: mismatch",
+		"\tö = \"héllo wörld\";",
+		"\t    ^~~~~~~~~~~~~",
+		"2:6: warning: no column",
+		"\tö = \"héllo wörld\";",
+		"\t    ^",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Fatalf("render wrong.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestRenderOutsideSource(t *testing.T) {
+	d := &Diagnostic{Severity: Warning, Pos: Pos{Offset: 90, Line: 7}, Message: "past the end"}
+
+	var out bytes.Buffer
+	r := &Renderer{Source: "program p: {}"}
+	r.Render(&out, DiagnosticList{d})
+
+	if out.String() != "7: warning: past the end\n" {
+		t.Fatalf("render wrong, got=%q", out.String())
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	var out bytes.Buffer
+	r := &Renderer{Source: "x = 1;", Color: true}
+	r.Render(&out, DiagnosticList{Errorf(Syntax, token.Token{Literal: "x", EndOffset: 1, Line: 1, Column: 1}, "boom")})
+
+	if !strings.Contains(out.String(), red) || !strings.Contains(out.String(), reset) {
+		t.Fatalf("expected ANSI colors, got=%q", out.String())
+	}
+}
@@ -4,19 +4,80 @@ package goyacc
 import __yyfmt__ "fmt"
 
 import (
+	"ciri/src/ast"
 	"ciri/src/token"
 )
 
-func setResult(l yyLexer, v []token.Token) {
-	l.(*Lexer).Tokens = v
+func setResult(l yyLexer, v *ast.Program) {
+	l.(*Lexer).program = v
+}
+
+func newIdent(tok token.Token) *ast.Ident {
+	return &ast.Ident{Token: tok, Name: tok.Literal}
+}
+
+// declName is a declared name with the dimensions of arrays
+type declName struct {
+	ident *ast.Ident
+	dims  []ast.Expression
+}
+
+func newVarDecl(names []declName, typ token.Token) *ast.VarDecl {
+	decl := &ast.VarDecl{Token: names[0].ident.Token, Type: typ}
+	for _, n := range names {
+		decl.Names = append(decl.Names, n.ident)
+		decl.Dims = append(decl.Dims, n.dims)
+	}
+	return decl
+}
+
+// newInitDecl declares a single variable with an initial value, its type
+// is inferred when typ is the zero token
+func newInitDecl(name token.Token, typ token.Token, value ast.Expression) *ast.VarDecl {
+	return &ast.VarDecl{Token: name, Names: []*ast.Ident{newIdent(name)}, Dims: [][]ast.Expression{nil}, Type: typ, Value: value}
+}
+
+// newTypedInitDecl keeps every name so the checker can report initializing
+// several names or an array
+func newTypedInitDecl(names []declName, typ token.Token, value ast.Expression) *ast.VarDecl {
+	decl := newVarDecl(names, typ)
+	decl.Value = value
+	return decl
+}
+
+// newFunction keeps the declarations that open the body of a function as
+// its local variables, later ones are scoped to the body
+func newFunction(tok token.Token, name *ast.Ident, params []*ast.Param, result token.Token, body *ast.Block) *ast.Function {
+	f := &ast.Function{Token: tok, Name: name, Params: params, Result: result, Body: body}
+	for len(body.Statements) > 0 {
+		decl, ok := body.Statements[0].(*ast.DeclStmt)
+		if !ok {
+			break
+		}
+		f.Vars = append(f.Vars, decl.Decl)
+		body.Statements = body.Statements[1:]
+	}
+	return f
+}
+
+func newBinary(op token.Token, left, right ast.Expression) *ast.BinaryExpr {
+	return &ast.BinaryExpr{Token: op, Operator: op.Literal, Left: left, Right: right}
 }
 
 type yySymType struct {
-	yys int
-	St  string
-	Fl  float64
-	In  int
-	Ch  byte
+	yys    int
+	Tok    token.Token
+	Prog   *ast.Program
+	Decls  []*ast.VarDecl
+	Funcs  []*ast.Function
+	Func   *ast.Function
+	Params []*ast.Param
+	Names  []declName
+	Block  *ast.Block
+	Stmt   ast.Statement
+	Stmts  []ast.Statement
+	Expr   ast.Expression
+	Exprs  []ast.Expression
 }
 
 const CTE_F = 57346
@@ -24,13 +85,33 @@ const CTE_I = 57347
 const VAR = 57348
 const IF = 57349
 const ELSE = 57350
-const ID = 57351
-const CTE_STRING = 57352
-const INT_TYPE = 57353
-const FLOAT_TYPE = 57354
-const PROGRAM = 57355
-const PRINT = 57356
-const UMINUS = 57357
+const WHILE = 57351
+const FOR = 57352
+const TO = 57353
+const STEP = 57354
+const ID = 57355
+const CTE_STRING = 57356
+const INT_TYPE = 57357
+const FLOAT_TYPE = 57358
+const BOOL_TYPE = 57359
+const STRING_TYPE = 57360
+const TRUE = 57361
+const FALSE = 57362
+const AND = 57363
+const OR = 57364
+const EQUAL = 57365
+const NOT_EQUAL = 57366
+const LESS_EQUAL = 57367
+const GREATER_EQUAL = 57368
+const LESS_THEN_GREAT = 57369
+const SHIFT_LEFT = 57370
+const SHIFT_RIGHT = 57371
+const PROGRAM = 57372
+const PRINT = 57373
+const FUNC = 57374
+const RETURN = 57375
+const ILLEGAL = 57376
+const UMINUS = 57377
 
 var yyToknames = [...]string{
 	"$end",
@@ -41,30 +122,55 @@ var yyToknames = [...]string{
 	"VAR",
 	"IF",
 	"ELSE",
+	"WHILE",
+	"FOR",
+	"TO",
+	"STEP",
 	"ID",
 	"CTE_STRING",
 	"INT_TYPE",
 	"FLOAT_TYPE",
+	"BOOL_TYPE",
+	"STRING_TYPE",
+	"TRUE",
+	"FALSE",
+	"AND",
+	"OR",
+	"EQUAL",
+	"NOT_EQUAL",
+	"LESS_EQUAL",
+	"GREATER_EQUAL",
+	"LESS_THEN_GREAT",
+	"SHIFT_LEFT",
+	"SHIFT_RIGHT",
 	"PROGRAM",
 	"PRINT",
-	"'|'",
-	"'&'",
+	"FUNC",
+	"RETURN",
+	"ILLEGAL",
 	"'+'",
 	"'-'",
 	"'*'",
 	"'/'",
 	"'%'",
-	"UMINUS",
-	"':'",
-	"';'",
-	"','",
+	"'&'",
+	"'|'",
+	"'^'",
+	"'~'",
+	"'<'",
+	"'>'",
+	"'!'",
 	"'{'",
 	"'}'",
 	"'('",
 	"')'",
+	"'['",
+	"']'",
 	"'='",
-	"'>'",
-	"'<'",
+	"';'",
+	"':'",
+	"','",
+	"UMINUS",
 }
 
 var yyStatenames = [...]string{}
@@ -77,102 +183,230 @@ var yyExca = [...]int{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 16,
+	48, 31,
+	-2, 0,
+	-1, 24,
+	32, 17,
+	47, 17,
+	-2, 0,
+	-1, 27,
+	48, 31,
+	-2, 0,
+	-1, 97,
+	32, 17,
+	47, 17,
+	-2, 0,
+	-1, 125,
+	32, 17,
+	47, 17,
+	-2, 0,
+	-1, 182,
+	32, 17,
+	47, 17,
+	-2, 0,
+	-1, 183,
+	48, 31,
+	-2, 0,
+	-1, 184,
+	48, 31,
+	-2, 0,
+	-1, 194,
+	48, 31,
+	-2, 0,
+	-1, 202,
+	55, 9,
+	-2, 58,
+	-1, 216,
+	55, 10,
+	-2, 59,
+	-1, 217,
+	48, 31,
+	-2, 0,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 92
+const yyLast = 341
 
 var yyAct = [...]int{
-	7, 62, 53, 46, 33, 9, 39, 47, 34, 24,
-	44, 43, 44, 43, 74, 42, 48, 42, 51, 52,
-	72, 61, 26, 40, 41, 40, 41, 25, 22, 8,
-	63, 82, 31, 45, 37, 21, 37, 80, 50, 49,
-	20, 4, 57, 56, 2, 58, 10, 59, 60, 54,
-	55, 18, 11, 17, 3, 65, 66, 67, 19, 12,
-	28, 29, 73, 68, 69, 70, 71, 75, 30, 44,
-	43, 76, 77, 23, 42, 79, 6, 81, 32, 35,
-	83, 38, 36, 78, 16, 15, 14, 13, 64, 27,
-	5, 1,
+	44, 196, 68, 20, 177, 201, 174, 49, 58, 205,
+	13, 199, 47, 48, 46, 197, 10, 15, 64, 73,
+	74, 178, 25, 67, 175, 35, 50, 12, 60, 59,
+	22, 224, 65, 79, 229, 21, 35, 57, 63, 84,
+	185, 184, 91, 61, 62, 180, 93, 94, 22, 22,
+	128, 141, 117, 21, 21, 129, 126, 125, 77, 54,
+	55, 122, 23, 22, 78, 19, 4, 53, 21, 220,
+	52, 218, 217, 51, 211, 123, 209, 208, 92, 118,
+	119, 120, 121, 183, 182, 168, 137, 130, 131, 133,
+	78, 136, 127, 97, 85, 139, 12, 24, 135, 86,
+	124, 94, 22, 22, 191, 176, 172, 171, 145, 146,
+	147, 148, 144, 143, 170, 169, 162, 142, 149, 150,
+	151, 152, 153, 154, 155, 140, 90, 165, 163, 166,
+	88, 87, 167, 43, 223, 76, 173, 16, 156, 157,
+	158, 159, 160, 161, 181, 164, 108, 109, 106, 107,
+	110, 194, 9, 200, 100, 101, 2, 98, 100, 101,
+	102, 103, 115, 116, 102, 103, 99, 105, 104, 14,
+	215, 111, 112, 113, 114, 66, 192, 96, 89, 193,
+	11, 82, 83, 195, 18, 3, 204, 186, 187, 188,
+	189, 210, 35, 35, 84, 84, 203, 207, 213, 212,
+	214, 14, 198, 35, 190, 75, 216, 69, 70, 71,
+	72, 7, 66, 222, 206, 6, 1, 179, 45, 56,
+	17, 221, 36, 227, 219, 138, 35, 228, 84, 225,
+	230, 134, 34, 60, 59, 132, 33, 60, 59, 226,
+	29, 32, 57, 63, 31, 30, 57, 63, 61, 62,
+	27, 81, 61, 62, 95, 80, 8, 5, 0, 28,
+	37, 0, 38, 39, 54, 55, 202, 0, 54, 55,
+	0, 0, 53, 0, 0, 52, 53, 0, 51, 52,
+	60, 59, 51, 0, 40, 0, 41, 0, 0, 57,
+	63, 0, 0, 0, 0, 61, 62, 80, 0, 0,
+	0, 28, 37, 0, 38, 39, 0, 0, 42, 26,
+	0, 54, 55, 28, 37, 0, 38, 39, 0, 53,
+	42, 0, 52, 0, 0, 51, 40, 0, 41, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 40, 0,
+	41,
 }
 
 var yyPact = [...]int{
-	31, -1000, 45, 18, 70, 3, 43, -1000, 44, -1000,
-	17, 10, 1, 44, -1000, -1000, -1000, -21, -1, -6,
-	49, 43, -1000, -1000, 8, 8, 6, 15, -1000, -1000,
-	-1000, 14, -1000, -13, 32, -1000, 23, 8, -1000, -1000,
-	65, 65, -1000, -1000, -1000, -8, 5, -1000, -1000, 43,
-	-1000, 8, 8, -1000, 8, 8, 8, 8, -9, -1000,
-	-1000, 3, -15, 6, -1000, -1000, -1000, -1000, 32, 32,
-	-1000, -1000, -1000, 67, 13, 5, -1000, -1000, 7, 3,
-	-1000, -1000, -1000, -1000,
+	126, -1000, 172, 11, 209, 120, 167, 90, 120, 171,
+	-1000, 12, -1000, 7, 43, -1000, 307, -1000, 84, 276,
+	-38, 162, 276, 192, 199, 87, 10, 295, 168, -1000,
+	-1000, -1000, -1000, -1000, -1000, 40, 46, 82, 81, 165,
+	77, 24, 52, 164, 39, 135, 145, -1000, 123, 134,
+	-1000, 276, 276, 276, 276, 276, -1000, 52, -1000, -1000,
+	-1000, -1000, -1000, -1000, 162, -1000, -21, 48, 3, -1000,
+	-1000, -1000, -1000, -1000, -1000, 167, -1000, -1000, -1000, -1000,
+	36, -1000, -3, -1000, 0, -1000, 276, 233, 229, 45,
+	276, 32, -1000, -1000, 276, 75, -4, 199, 276, 276,
+	276, 276, 276, 276, 276, 276, 276, 276, 276, 276,
+	276, 276, 276, 276, 276, 276, 276, 66, -1000, -1000,
+	-1000, -1000, -1000, -1000, 51, 199, 276, -1000, 276, 192,
+	31, 65, 64, 57, 56, 276, -32, -1000, 55, -35,
+	-10, 192, -1000, 145, -1000, 134, 134, 134, 134, 119,
+	119, 119, 119, 119, 119, 119, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 30, 29, -13, -1000, 90,
+	90, 90, 90, 193, 54, 276, -1000, -1000, 276, 104,
+	192, -41, 199, 253, 253, 276, 206, 206, 23, 22,
+	276, 20, -32, -35, 295, -1000, -1000, 157, -1000, -1000,
+	-1000, -1000, -2, -1000, 18, 17, 90, 15, -1000, -1000,
+	201, -1000, -1000, -1000, 86, -24, -38, 253, -1000, -1000,
+	-1000, 90, 276, -1000, 192, -1000, -20, -1000, -41, -1000,
+	-1000,
 }
 
 var yyPgo = [...]int{
-	0, 91, 90, 0, 5, 46, 89, 88, 59, 87,
-	86, 85, 84, 7, 83, 3, 1, 6, 82, 81,
-	8, 79, 4, 2, 78,
+	0, 257, 16, 20, 19, 10, 211, 256, 254, 1,
+	17, 9, 5, 251, 153, 11, 250, 245, 244, 241,
+	240, 236, 232, 6, 225, 4, 3, 222, 8, 221,
+	219, 26, 7, 13, 0, 218, 14, 12, 2, 217,
+	216,
 }
 
 var yyR1 = [...]int{
-	0, 1, 2, 2, 4, 5, 5, 7, 7, 3,
-	8, 8, 9, 9, 9, 11, 14, 14, 10, 12,
-	15, 15, 16, 16, 6, 6, 17, 17, 17, 18,
-	18, 19, 19, 19, 20, 21, 21, 21, 22, 23,
-	23, 23, 13, 24, 24, 24,
+	0, 40, 1, 1, 2, 2, 3, 3, 3, 5,
+	5, 5, 5, 26, 26, 4, 4, 4, 6, 6,
+	7, 8, 8, 9, 9, 39, 39, 10, 10, 12,
+	12, 12, 13, 13, 14, 14, 15, 15, 16, 16,
+	16, 16, 16, 16, 16, 16, 17, 17, 11, 11,
+	18, 18, 19, 29, 29, 22, 22, 20, 27, 27,
+	21, 23, 23, 38, 38, 38, 38, 30, 30, 30,
+	30, 30, 30, 30, 30, 28, 24, 24, 25, 25,
+	31, 31, 31, 31, 31, 31, 32, 32, 32, 32,
+	32, 32, 32, 33, 33, 33, 33, 33, 34, 35,
+	35, 36, 36, 37, 37, 37, 37, 37, 37, 37,
+	37,
 }
 
 var yyR2 = [...]int{
-	0, 5, 2, 0, 5, 1, 3, 1, 0, 3,
-	2, 0, 1, 1, 1, 7, 2, 0, 4, 6,
-	1, 1, 3, 0, 1, 1, 1, 1, 1, 3,
-	1, 1, 2, 2, 1, 1, 3, 3, 2, 3,
-	3, 0, 1, 3, 3, 1,
+	0, 6, 2, 0, 5, 1, 5, 7, 3, 1,
+	2, 3, 4, 3, 4, 1, 2, 0, 2, 0,
+	9, 4, 0, 5, 0, 2, 0, 3, 3, 2,
+	2, 0, 5, 1, 5, 7, 1, 1, 1, 1,
+	1, 1, 1, 1, 2, 2, 7, 7, 2, 0,
+	6, 6, 9, 2, 0, 3, 2, 4, 1, 2,
+	6, 3, 0, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 4, 2, 0, 3, 0,
+	3, 2, 2, 2, 2, 1, 3, 3, 3, 3,
+	3, 3, 1, 3, 3, 3, 3, 1, 1, 3,
+	1, 3, 1, 3, 3, 3, 3, 3, 3, 3,
+	1,
 }
 
 var yyChk = [...]int{
-	-1000, -1, 13, 9, 23, -2, 6, -3, 26, -4,
-	-5, 9, -8, -9, -10, -11, -12, 9, 7, 14,
-	23, 25, 27, -8, 30, 28, 28, -6, 11, 12,
-	-5, -13, -24, -22, -20, -21, -18, 28, -19, -17,
-	17, 18, 9, 5, 4, -13, -15, -13, 10, 24,
-	24, 31, 32, -23, 17, 18, 20, 19, -13, -17,
-	-17, 29, -16, 25, -7, -4, -22, -22, -20, -20,
-	-20, -20, 29, -3, 29, -15, -23, -23, -14, 8,
-	24, -16, 24, -3,
+	-1000, -40, 30, 13, 55, -1, 6, -6, -7, 32,
+	-2, 13, -3, -5, 2, -10, 47, -6, 13, 53,
+	-26, 56, 51, 55, 54, -12, 2, -16, 6, -20,
+	-17, -18, -19, -21, -22, -28, -27, 7, 9, 10,
+	31, 33, 13, 49, -34, -35, -36, -37, -33, -32,
+	-31, 49, 46, 43, 35, 36, -30, 13, -28, 5,
+	4, 19, 20, 14, 56, -5, 13, -34, -38, 15,
+	16, 17, 18, -4, -3, 6, 48, 48, 54, -12,
+	2, -13, 13, -14, -5, 54, 53, 49, 49, 13,
+	49, -34, 54, -26, 49, -8, 13, 54, 22, 21,
+	35, 36, 41, 42, 45, 44, 25, 26, 23, 24,
+	27, 37, 38, 39, 40, 28, 29, -34, -31, -31,
+	-31, -31, -26, -5, 52, 54, 53, -2, 53, 55,
+	-34, -34, 2, -34, 2, 53, -34, 54, -24, -34,
+	50, 55, -4, -36, -37, -32, -32, -32, -32, -33,
+	-33, -33, -33, -33, -33, -33, -31, -31, -31, -31,
+	-31, -31, 50, -26, -4, -34, -34, -38, 54, 50,
+	50, 50, 50, -34, -23, 56, 50, -25, 56, -39,
+	55, -38, 54, 54, 54, 53, -10, -10, -10, -10,
+	11, 50, -34, -34, 47, -38, -9, 56, -4, -15,
+	-14, -12, 13, -15, -34, -11, 8, -11, 54, 54,
+	-34, 54, -23, -25, -12, 13, -26, 54, 54, -10,
+	54, -29, 12, 48, 55, -15, -10, -34, -38, 54,
+	-9,
 }
 
 var yyDef = [...]int{
-	0, -2, 0, 0, 3, 0, 0, 1, 11, 2,
-	0, 5, 0, 11, 12, 13, 14, 0, 0, 0,
-	0, 0, 9, 10, 0, 0, 0, 0, 24, 25,
-	6, 0, 42, 45, 41, 34, 35, 0, 30, 31,
-	0, 0, 26, 27, 28, 0, 23, 20, 21, 8,
-	18, 0, 0, 38, 0, 0, 0, 0, 0, 32,
-	33, 0, 0, 0, 4, 7, 43, 44, 41, 41,
-	36, 37, 29, 17, 0, 23, 39, 40, 0, 0,
-	19, 22, 15, 16,
+	0, -2, 0, 0, 3, 19, 0, 0, 19, 0,
+	2, 9, 5, 0, 0, 1, -2, 18, 0, 0,
+	10, 0, 0, 0, -2, 0, 0, -2, 0, 38,
+	39, 40, 41, 42, 43, 0, 0, 0, 0, 0,
+	0, 0, 58, 22, 0, 98, 100, 102, 110, 97,
+	92, 0, 0, 0, 0, 0, 85, 67, 69, 70,
+	71, 72, 73, 74, 0, 11, 9, 0, 0, 63,
+	64, 65, 66, 8, 15, 0, 27, 28, 45, 29,
+	0, 30, 9, 33, 0, 44, 0, 0, 0, 0,
+	0, 0, 56, 59, 77, 0, 0, -2, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 81, 82,
+	83, 84, 68, 12, 13, -2, 0, 16, 0, 0,
+	0, 0, 0, 0, 0, 0, 62, 55, 0, 79,
+	26, 0, 4, 99, 101, 93, 94, 95, 96, 103,
+	104, 105, 106, 107, 108, 109, 86, 87, 88, 89,
+	90, 91, 80, 14, 6, 0, 0, 0, 57, 0,
+	0, 0, 0, 0, 0, 0, 75, 76, 0, 0,
+	0, 24, -2, -2, -2, 0, 49, 49, 0, 0,
+	0, 0, 62, 79, -2, 25, 21, 0, 7, 32,
+	36, 37, -2, 34, 0, 0, 0, 0, 50, 51,
+	54, 60, 61, 78, 0, 0, -2, -2, 46, 48,
+	47, 0, 0, 20, 0, 35, 0, 53, 24, 52,
+	23,
 }
 
 var yyTok1 = [...]int{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 21, 16, 3,
-	28, 29, 19, 17, 25, 18, 3, 20, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 23, 24,
-	32, 30, 31, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 46, 3, 3, 3, 39, 40, 3,
+	49, 50, 37, 35, 56, 36, 3, 38, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 55, 54,
+	44, 53, 45, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 51, 3, 52, 42, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 26, 15, 27,
+	3, 3, 3, 47, 41, 48, 43,
 }
 
 var yyTok2 = [...]int{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 22,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
+	32, 33, 34, 57,
 }
 
 var yyTok3 = [...]int{
@@ -514,6 +748,441 @@ yydefault:
 	// dummy call; replaced with literal code
 	switch yynt {
 
+	case 1:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			setResult(yylex, &ast.Program{Token: yyDollar[1].Tok, Name: newIdent(yyDollar[2].Tok), Vars: yyDollar[4].Decls, Funcs: yyDollar[5].Funcs, Body: yyDollar[6].Block})
+		}
+	case 2:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Decls = yyDollar[2].Decls
+		}
+	case 3:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Decls = nil
+		}
+	case 4:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.Decls = append([]*ast.VarDecl{newInitDecl(yyDollar[1].Tok, token.Token{}, yyDollar[3].Expr)}, yyDollar[5].Decls...)
+		}
+	case 6:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.Decls = append([]*ast.VarDecl{newVarDecl(yyDollar[1].Names, yyDollar[3].Tok)}, yyDollar[5].Decls...)
+		}
+	case 7:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.Decls = append([]*ast.VarDecl{newTypedInitDecl(yyDollar[1].Names, yyDollar[3].Tok, yyDollar[5].Expr)}, yyDollar[7].Decls...)
+		}
+	case 8:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Decls = yyDollar[3].Decls
+		}
+	case 9:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Names = []declName{{ident: newIdent(yyDollar[1].Tok)}}
+		}
+	case 10:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Names = []declName{{ident: newIdent(yyDollar[1].Tok), dims: yyDollar[2].Exprs}}
+		}
+	case 11:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Names = append([]declName{{ident: newIdent(yyDollar[1].Tok)}}, yyDollar[3].Names...)
+		}
+	case 12:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.Names = append([]declName{{ident: newIdent(yyDollar[1].Tok), dims: yyDollar[2].Exprs}}, yyDollar[4].Names...)
+		}
+	case 13:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Exprs = []ast.Expression{yyDollar[2].Expr}
+		}
+	case 14:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.Exprs = append([]ast.Expression{yyDollar[2].Expr}, yyDollar[4].Exprs...)
+		}
+	case 16:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Decls = yyDollar[2].Decls
+		}
+	case 17:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Decls = nil
+		}
+	case 18:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Funcs = append([]*ast.Function{yyDollar[1].Func}, yyDollar[2].Funcs...)
+		}
+	case 19:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Funcs = nil
+		}
+	case 20:
+		yyDollar = yyS[yypt-9 : yypt+1]
+		{
+			yyVAL.Func = newFunction(yyDollar[1].Tok, newIdent(yyDollar[2].Tok), yyDollar[4].Params, yyDollar[6].Tok, &ast.Block{Token: yyDollar[7].Tok, Statements: yyDollar[8].Stmts})
+		}
+	case 21:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.Params = append([]*ast.Param{{Name: newIdent(yyDollar[1].Tok), Type: yyDollar[3].Tok}}, yyDollar[4].Params...)
+		}
+	case 22:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Params = nil
+		}
+	case 23:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.Params = append([]*ast.Param{{Name: newIdent(yyDollar[2].Tok), Type: yyDollar[4].Tok}}, yyDollar[5].Params...)
+		}
+	case 24:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Params = nil
+		}
+	case 25:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Tok = yyDollar[2].Tok
+		}
+	case 26:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Tok = token.Token{}
+		}
+	case 27:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Block = &ast.Block{Token: yyDollar[1].Tok, Statements: yyDollar[2].Stmts}
+		}
+	case 28:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Block = &ast.Block{Token: yyDollar[1].Tok, Statements: []ast.Statement{&ast.BadStmt{Token: yyDollar[1].Tok}}}
+		}
+	case 29:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Stmts = append([]ast.Statement{yyDollar[1].Stmt}, yyDollar[2].Stmts...)
+		}
+	case 30:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Stmts = yyDollar[2].Stmts
+		}
+	case 31:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Stmts = nil
+		}
+	case 32:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.Stmts = append([]ast.Statement{&ast.DeclStmt{Decl: newInitDecl(yyDollar[1].Tok, token.Token{}, yyDollar[3].Expr)}}, yyDollar[5].Stmts...)
+		}
+	case 34:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.Stmts = append([]ast.Statement{&ast.DeclStmt{Decl: newVarDecl(yyDollar[1].Names, yyDollar[3].Tok)}}, yyDollar[5].Stmts...)
+		}
+	case 35:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.Stmts = append([]ast.Statement{&ast.DeclStmt{Decl: newTypedInitDecl(yyDollar[1].Names, yyDollar[3].Tok, yyDollar[5].Expr)}}, yyDollar[7].Stmts...)
+		}
+	case 44:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.CallStmt{Call: yyDollar[1].Expr.(*ast.Call)}
+		}
+	case 45:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.BadStmt{Token: yyDollar[2].Tok}
+		}
+	case 46:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.If{Token: yyDollar[1].Tok, Condition: yyDollar[3].Expr, Consequence: yyDollar[5].Block, Alternative: yyDollar[6].Block}
+		}
+	case 47:
+		yyDollar = yyS[yypt-7 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.BadStmt{Token: yyDollar[1].Tok}
+		}
+	case 48:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Block = yyDollar[2].Block
+		}
+	case 49:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Block = nil
+		}
+	case 50:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.While{Token: yyDollar[1].Tok, Condition: yyDollar[3].Expr, Body: yyDollar[5].Block}
+		}
+	case 51:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.BadStmt{Token: yyDollar[1].Tok}
+		}
+	case 52:
+		yyDollar = yyS[yypt-9 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.For{Token: yyDollar[1].Tok, Var: newIdent(yyDollar[2].Tok), Start: yyDollar[4].Expr, End: yyDollar[6].Expr, Step: yyDollar[7].Expr, Body: yyDollar[8].Block}
+		}
+	case 53:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = yyDollar[2].Expr
+		}
+	case 54:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Expr = nil
+		}
+	case 55:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.Return{Token: yyDollar[1].Tok, Value: yyDollar[2].Expr}
+		}
+	case 56:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.Return{Token: yyDollar[1].Tok}
+		}
+	case 57:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.Assign{Token: yyDollar[1].Expr.Pos(), Target: yyDollar[1].Expr, Value: yyDollar[3].Expr}
+		}
+	case 58:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = newIdent(yyDollar[1].Tok)
+		}
+	case 59:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Index{Token: yyDollar[1].Tok, Array: newIdent(yyDollar[1].Tok), Indices: yyDollar[2].Exprs}
+		}
+	case 60:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.Stmt = &ast.Print{Token: yyDollar[1].Tok, Args: append([]ast.Expression{yyDollar[3].Expr}, yyDollar[4].Exprs...)}
+		}
+	case 61:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Exprs = append([]ast.Expression{yyDollar[2].Expr}, yyDollar[3].Exprs...)
+		}
+	case 62:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Exprs = nil
+		}
+	case 67:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = newIdent(yyDollar[1].Tok)
+		}
+	case 68:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Index{Token: yyDollar[1].Tok, Array: newIdent(yyDollar[1].Tok), Indices: yyDollar[2].Exprs}
+		}
+	case 70:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Literal{Token: yyDollar[1].Tok}
+		}
+	case 71:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Literal{Token: yyDollar[1].Tok}
+		}
+	case 72:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Literal{Token: yyDollar[1].Tok}
+		}
+	case 73:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Literal{Token: yyDollar[1].Tok}
+		}
+	case 74:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Literal{Token: yyDollar[1].Tok}
+		}
+	case 75:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.Expr = &ast.Call{Token: yyDollar[1].Tok, Function: newIdent(yyDollar[1].Tok), Args: yyDollar[3].Exprs}
+		}
+	case 76:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Exprs = append([]ast.Expression{yyDollar[1].Expr}, yyDollar[2].Exprs...)
+		}
+	case 77:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Exprs = nil
+		}
+	case 78:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Exprs = append([]ast.Expression{yyDollar[2].Expr}, yyDollar[3].Exprs...)
+		}
+	case 79:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.Exprs = nil
+		}
+	case 80:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = yyDollar[2].Expr
+		}
+	case 81:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.UnaryExpr{Token: yyDollar[1].Tok, Operator: yyDollar[1].Tok.Literal, Operand: yyDollar[2].Expr}
+		}
+	case 82:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.UnaryExpr{Token: yyDollar[1].Tok, Operator: yyDollar[1].Tok.Literal, Operand: yyDollar[2].Expr}
+		}
+	case 83:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.UnaryExpr{Token: yyDollar[1].Tok, Operator: yyDollar[1].Tok.Literal, Operand: yyDollar[2].Expr}
+		}
+	case 84:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.Expr = &ast.UnaryExpr{Token: yyDollar[1].Tok, Operator: yyDollar[1].Tok.Literal, Operand: yyDollar[2].Expr}
+		}
+	case 86:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 87:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 88:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 89:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 90:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 91:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 93:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 94:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 95:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 96:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 99:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 101:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 103:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 104:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 105:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 106:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 107:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 108:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
+	case 109:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.Expr = newBinary(yyDollar[2].Tok, yyDollar[1].Expr, yyDollar[3].Expr)
+		}
 	}
 	goto yystack /* stack new state and value */
 }
@@ -1,9 +1,12 @@
 package goyacc
 
 import (
+	"ciri/src/ast"
+	"ciri/src/diag"
 	"ciri/src/token"
-	"errors"
-	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -12,29 +15,56 @@ type Lexer struct {
 	nextPosition  int
 	current       byte
 	lineNumber    uint32
+	lineStart     int
 	Tokens        []token.Token
 	lastReadToken token.Token
-	err           error
+	comments      []token.Token
+	diagnostics   diag.DiagnosticList
+	// unterminated is set when a string or comment runs into the end of
+	// the input, the syntax errors it causes there are not reported
+	unterminated bool
+	program      *ast.Program
 }
 
 func New(input string) *Lexer {
-	lexer := &Lexer{input: input, lineNumber: 0, Tokens: make([]token.Token, 0)}
+	lexer := &Lexer{input: input, lineNumber: 1, Tokens: make([]token.Token, 0)}
 	lexer.readChar()
 	return lexer
 }
 
-//NextToken reads the next token if valid
+// NextToken reads the next token if valid
 func (l *Lexer) NextToken() token.Token {
 	var t token.Token
 	l.ignoreWhitespaces()
+	start, line, lineStart := l.position, l.lineNumber, l.lineStart
 
 	switch l.current {
 	case '=':
-		t = l.newToken(token.ASSIGN)
+		if l.peekChar() == '=' {
+			t = l.newTwoCharToken(token.EQUAL)
+		} else {
+			t = l.newToken(token.ASSIGN)
+		}
 	case '<':
-		t = l.newToken(token.LESS_THAN)
+		switch l.peekChar() {
+		case '=':
+			t = l.newTwoCharToken(token.LESS_EQUAL)
+		case '>':
+			t = l.newTwoCharToken(token.LESS_THEN_GREAT)
+		case '<':
+			t = l.newTwoCharToken(token.SHIFT_LEFT)
+		default:
+			t = l.newToken(token.LESS_THAN)
+		}
 	case '>':
-		t = l.newToken(token.GREATER_THAN)
+		switch l.peekChar() {
+		case '=':
+			t = l.newTwoCharToken(token.GREATER_EQUAL)
+		case '>':
+			t = l.newTwoCharToken(token.SHIFT_RIGHT)
+		default:
+			t = l.newToken(token.GREATER_THAN)
+		}
 	case ';':
 		t = l.newToken(token.SEMICOLON)
 	case ':':
@@ -47,6 +77,10 @@ func (l *Lexer) NextToken() token.Token {
 		t = l.newToken(token.OPEN_BRACE)
 	case '}':
 		t = l.newToken(token.CLOSED_BRACE)
+	case '[':
+		t = l.newToken(token.OPEN_BRACKET)
+	case ']':
+		t = l.newToken(token.CLOSED_BRACKET)
 	case '+':
 		t = l.newToken(token.PLUS)
 	case '-':
@@ -57,6 +91,30 @@ func (l *Lexer) NextToken() token.Token {
 		t = l.newToken(token.COMMA)
 	case '*':
 		t = l.newToken(token.MULTIPLY)
+	case '!':
+		if l.peekChar() == '=' {
+			t = l.newTwoCharToken(token.NOT_EQUAL)
+		} else {
+			t = l.newToken(token.NOT)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			t = l.newTwoCharToken(token.AND)
+		} else {
+			t = l.newToken(token.BIT_AND)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			t = l.newTwoCharToken(token.OR)
+		} else {
+			t = l.newToken(token.BIT_OR)
+		}
+	case '^':
+		t = l.newToken(token.BIT_XOR)
+	case '~':
+		t = l.newToken(token.BIT_NOT)
+	case '%':
+		t = l.newToken(token.MODULO)
 	case 0:
 		t.Literal = ""
 		t.Type = token.EOF
@@ -69,12 +127,13 @@ func (l *Lexer) NextToken() token.Token {
 		} else if isStringStart(l.current) {
 			t = l.lookupString()
 		} else {
-			t = l.newToken(token.ILLEGAL)
+			t = l.newIllegalToken()
 		}
 	}
 	if !t.IsKeyword {
 		l.readChar()
 	}
+	l.locate(&t, start, line, lineStart)
 	l.Tokens = append(l.Tokens, t)
 	l.lastReadToken = t
 	return t
@@ -82,17 +141,88 @@ func (l *Lexer) NextToken() token.Token {
 
 // Helpers
 
+// ignoreWhitespaces skips whitespace and comments, comments are kept as
+// trivia for tools that need them
 func (l *Lexer) ignoreWhitespaces() {
-	for l.current == ' ' || l.current == '\t' || l.current == '\n' || l.current == '\r' {
-		if l.current == '\n' {
-			l.lineNumber += 1
+	for {
+		switch {
+		case l.current == ' ' || l.current == '\t' || l.current == '\n' || l.current == '\r':
+			l.newLine()
+			l.readChar()
+		case l.current == '/' && l.peekChar() == '/':
+			l.readComment(false)
+		case l.current == '/' && l.peekChar() == '*':
+			l.readComment(true)
+		default:
+			return
 		}
+	}
+}
+
+// readComment skips a line or a block comment and records it as a COMMENT
+// token
+func (l *Lexer) readComment(block bool) {
+	start, line, lineStart := l.position, l.lineNumber, l.lineStart
+	if block {
+		l.skipBlockComment(start)
+	} else {
+		l.skipLineComment()
+	}
+	t := token.Token{Type: token.COMMENT, Literal: l.input[start:l.position]}
+	l.locate(&t, start, line, lineStart)
+	l.comments = append(l.comments, t)
+}
+
+// skipLineComment reads up to the end of the line
+func (l *Lexer) skipLineComment() {
+	for l.current != '\n' && l.current != 0 {
+		l.readChar()
+	}
+}
+
+// skipBlockComment reads past the */ closing the comment, block comments
+// nest so code holding one can be commented out
+func (l *Lexer) skipBlockComment(start int) {
+	t := token.Token{Literal: "/*"}
+	l.locate(&t, start, l.lineNumber, l.lineStart)
+	t.EndOffset = start + 2
+	l.readChar()
+
+	for depth := 1; depth > 0; {
 		l.readChar()
+		switch {
+		case l.current == 0:
+			l.diagnostics.Add(diag.Errorf(diag.OpenComment, t, "comment not terminated"))
+			l.unterminated = true
+			return
+		case l.current == '/' && l.peekChar() == '*':
+			l.readChar()
+			depth++
+		case l.current == '*' && l.peekChar() == '/':
+			l.readChar()
+			depth--
+		default:
+			l.newLine()
+		}
 	}
+	l.readChar()
+}
+
+// locate sets the position of a token that started at offset start of
+// line and ends at the current position
+func (l *Lexer) locate(t *token.Token, start int, line uint32, lineStart int) {
+	t.Offset = start
+	t.EndOffset = l.position
+	t.Line = line
+	t.Column = uint32(utf8.RuneCountInString(l.input[lineStart:start])) + 1
 }
 
 func isStringStart(ch byte) bool {
-	return ch == '"'
+	return ch == '"' || ch == '`'
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
 }
 
 func isLetter(ch byte) bool {
@@ -105,32 +235,25 @@ func isDigit(ch byte) bool {
 
 //Yacc interface
 
+// Comments returns the comments skipped so far in source order
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) GetError() error {
-	return l.err
+	return l.diagnostics.Err()
 }
 
-func (l *Lexer) Error(s string) {
-	s = fmt.Sprintf(" \n %s \n  %s", s, l.formatError())
-	l.err = errors.New(s)
-}
-
-func (l *Lexer) formatError() string {
-	//show last 5 Tokens
-	tokens := l.Tokens
-	output := ""
-	var tok token.Token
-	pos := len(tokens) - 5
-	if pos < 0 {
-		pos = 0
-	}
+// Diagnostics returns everything reported while lexing and parsing
+func (l *Lexer) Diagnostics() diag.DiagnosticList {
+	return l.diagnostics
+}
 
-	for pos < len(tokens) {
-		tok = tokens[pos]
-		output += tok.Literal + " "
-		pos += 1
+func (l *Lexer) Error(s string) {
+	if l.unterminated && l.lastReadToken.Type == token.EOF {
+		return
 	}
-
-	return fmt.Sprintf("at %s'%s' <--  \n in line: %d \n '%s' should not be here", output, l.lastReadToken.Literal, tok.LineNumber, l.lastReadToken.Literal)
+	l.diagnostics.Add(diag.Errorf(diag.Syntax, l.lastReadToken, "%s", syntaxMessage(s, l.lastReadToken)))
 }
 
 // Lookups
@@ -158,35 +281,138 @@ func (l *Lexer) resetLookup(old *Lexer) {
 	l.position = old.position
 	l.nextPosition = old.nextPosition
 	l.lineNumber = old.lineNumber
-
-	l.current = l.input[l.position]
+	l.lineStart = old.lineStart
+	l.current = old.current
 }
 
+// lookupString reads a quoted or a raw string, Literal keeps the source
+// text and Value the string it stands for. Strings may span lines, one that
+// is never closed is reported at its opening quote and ends with the input
 func (l *Lexer) lookupString() token.Token {
-	raw, unclosed := l.readRawString()
-	if unclosed {
-		return l.newToken(token.ILLEGAL)
+	start := l.position
+	quote := l.current
+	open := token.Token{Literal: string(quote)}
+	l.locate(&open, start, l.lineNumber, l.lineStart)
+	open.EndOffset = start + 1
+
+	var value strings.Builder
+	for {
+		l.readChar()
+		switch {
+		case l.current == 0:
+			l.diagnostics.Add(diag.Errorf(diag.OpenString, open, "string not terminated"))
+			l.unterminated = true
+			t := l.newKeywordToken(token.STRING, l.input[start:l.position])
+			t.Value = value.String()
+			return t
+		case l.current == quote:
+			l.readChar()
+			t := l.newKeywordToken(token.STRING, l.input[start:l.position])
+			t.Value = value.String()
+			return t
+		case l.current == '\\' && quote == '"':
+			l.readEscape(&value)
+		default:
+			l.newLine()
+			value.WriteByte(l.current)
+		}
 	}
-	return l.newKeywordToken(token.STRING, raw)
 }
 
-func (l *Lexer) lookupNumerics() token.Token {
-	lexCopy := l.makeCopy()
-	potentialFloat := readFloat(l)
+// readEscape decodes the escape sequence starting at the current backslash
+func (l *Lexer) readEscape(value *strings.Builder) {
+	start := l.position
+	l.readChar()
+	switch l.current {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '\\', '"':
+		value.WriteByte(l.current)
+	case 'x':
+		digits := l.readHex(2)
+		if len(digits) != 2 {
+			l.escapeError(start, "invalid escape sequence %s, \\x needs 2 hex digits", l.input[start:l.position+1])
+			return
+		}
+		b, _ := strconv.ParseUint(digits, 16, 8)
+		value.WriteByte(byte(b))
+	case 'u':
+		if l.peekChar() != '{' {
+			l.escapeError(start, "invalid escape sequence %s, expected \\u{...}", l.input[start:l.position+1])
+			return
+		}
+		l.readChar()
+		digits := l.readHex(6)
+		r, _ := strconv.ParseUint(digits, 16, 32)
+		if l.peekChar() != '}' || digits == "" || !utf8.ValidRune(rune(r)) {
+			l.escapeError(start, "invalid escape sequence %s, expected a unicode code point", l.input[start:l.position+1])
+			return
+		}
+		l.readChar()
+		value.WriteRune(rune(r))
+	case 0:
+		// the string is not closed, lookupString reports it
+	default:
+		_, size := utf8.DecodeRuneInString(l.input[l.position:])
+		for i := 1; i < size; i++ {
+			l.readChar()
+		}
+		l.escapeError(start, "unknown escape sequence %s", l.input[start:l.position+1])
+		l.newLine()
+	}
+}
 
-	tokenType := token.LookupIdentifier(token.FLOAT_IDENT, potentialFloat)
-	if tokenType != token.ILLEGAL {
-		return l.newKeywordToken(tokenType, potentialFloat)
+// readHex reads up to max hex digits following the current character
+func (l *Lexer) readHex(max int) string {
+	start := l.position + 1
+	for i := 0; i < max && isHexDigit(l.peekChar()); i++ {
+		l.readChar()
 	}
+	return l.input[start : l.position+1]
+}
 
-	l.resetLookup(lexCopy)
-	potentialInt := readInt(l)
-	tokenType = token.LookupIdentifier(token.INT_IDENT, potentialInt)
-	if tokenType != token.ILLEGAL {
-		return l.newKeywordToken(tokenType, potentialInt)
+// newLine keeps the line count right when a token spans lines
+func (l *Lexer) newLine() {
+	if l.current == '\n' {
+		l.lineNumber += 1
+		l.lineStart = l.position + 1
 	}
+}
 
-	return l.newToken(token.ILLEGAL)
+// escapeError reports the escape sequence from start to the current
+// character, the string itself is still returned so parsing goes on
+func (l *Lexer) escapeError(start int, format string, args ...interface{}) {
+	t := token.Token{Literal: l.input[start : l.position+1]}
+	l.locate(&t, start, l.lineNumber, l.lineStart)
+	t.EndOffset = l.position + 1
+	l.diagnostics.Add(diag.Errorf(diag.InvalidEscape, t, format, args...))
+}
+
+// lookupNumerics reads everything that can be part of a number so a
+// malformed one like 1.2.3 is a single illegal token
+func (l *Lexer) lookupNumerics() token.Token {
+	start := l.position
+	hex := l.current == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X')
+	for {
+		prev := l.current
+		l.readChar()
+		exponent := !hex && (prev == 'e' || prev == 'E') && (l.current == '+' || l.current == '-')
+		if !exponent && !isLetter(l.current) && !isDigit(l.current) && l.current != '.' {
+			break
+		}
+	}
+	potentialNumber := l.input[start:l.position]
+
+	for _, keyword := range []token.Keyword{token.INT_IDENT, token.FLOAT_IDENT} {
+		if tokenType := token.LookupIdentifier(keyword, potentialNumber); tokenType != token.ILLEGAL {
+			return l.newKeywordToken(tokenType, potentialNumber)
+		}
+	}
+	return l.newKeywordToken(token.ILLEGAL, potentialNumber)
 }
 
 // Lookup helpers
@@ -194,6 +420,7 @@ func (l *Lexer) lookupNumerics() token.Token {
 func (l *Lexer) readChar() {
 	if l.nextPosition >= len(l.input) {
 		l.current = 0
+		l.position = len(l.input)
 		return
 	}
 	l.current = l.input[l.nextPosition]
@@ -208,29 +435,10 @@ func (l *Lexer) makeCopy() *Lexer {
 		nextPosition: l.nextPosition,
 		current:      l.current,
 		lineNumber:   l.lineNumber,
+		lineStart:    l.lineStart,
 	}
 }
 
-func readFloat(l *Lexer) string {
-	keyword := ""
-
-	for l.current == '.' || isDigit(l.current) {
-		keyword += string(l.current)
-		l.readChar()
-	}
-	return keyword
-}
-
-func readInt(l *Lexer) string {
-	keyword := ""
-
-	for isDigit(l.current) {
-		keyword += string(l.current)
-		l.readChar()
-	}
-	return keyword
-}
-
 func readWord(l *Lexer) string {
 	keyword := ""
 
@@ -253,136 +461,174 @@ func readAlphaNumeric(l *Lexer) string {
 
 // Token Builders
 
+// Positions are filled in by locate once the token is read
+
 func (l *Lexer) newKeywordToken(tokenType token.Type, keyword string) token.Token {
 	return token.Token{
-		Type:            tokenType,
-		LineNumber:      l.lineNumber,
-		CharacterNumber: uint32(l.position - len(keyword) - 1),
-		Literal:         keyword,
-		IsKeyword:       true,
+		Type:      tokenType,
+		Literal:   keyword,
+		IsKeyword: true,
 	}
 }
 
 func (l *Lexer) newToken(tokeType token.Type) token.Token {
 	return token.Token{
-		Type:            tokeType,
-		Literal:         string(l.current),
-		LineNumber:      l.lineNumber,
-		CharacterNumber: uint32(l.position),
-		IsKeyword:       false,
+		Type:      tokeType,
+		Literal:   string(l.current),
+		IsKeyword: false,
 	}
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.nextPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.nextPosition]
+// newTwoCharToken reads operators like && whose second character is the
+// next one
+func (l *Lexer) newTwoCharToken(tokenType token.Type) token.Token {
+	first := l.current
+	l.readChar()
+	return token.Token{
+		Type:      tokenType,
+		Literal:   string(first) + string(l.current),
+		IsKeyword: false,
 	}
 }
 
-func (l *Lexer) readRawString() (string, bool) {
-	unclosedError := false
-	output := string(l.current)
-	for {
+// newIllegalToken reads a whole UTF-8 character so columns stay right
+func (l *Lexer) newIllegalToken() token.Token {
+	r, size := utf8.DecodeRuneInString(l.input[l.position:])
+	for i := 1; i < size; i++ {
 		l.readChar()
-		if l.current == '"' || l.current == 0 {
-			if l.current == 0 {
-				unclosedError = true
-			} else {
-				output += string(l.current)
-				l.readChar()
-			}
-
-			break
-		}
-		output += string(l.current)
 	}
+	return token.Token{
+		Type:      token.ILLEGAL,
+		Literal:   string(r),
+		IsKeyword: false,
+	}
+}
 
-	return output, unclosedError
+func (l *Lexer) peekChar() byte {
+	if l.nextPosition >= len(l.input) {
+		return 0
+	} else {
+		return l.input[l.nextPosition]
+	}
 }
 
 func (l *Lexer) Lex(parserVal *yySymType) int {
 	tok := l.NextToken()
+	for tok.Type == token.ILLEGAL {
+		l.diagnostics.Add(diag.Errorf(diag.IllegalToken, tok, "illegal token %q", tok.Literal))
+		tok = l.NextToken()
+	}
+	parserVal.Tok = tok
 
 	switch tok.Type {
 	case token.VAR:
-		parserVal.St = tok.Literal
 		return VAR
 	case token.ID:
-		parserVal.St = tok.Literal
 		return ID
 	case token.FLOAT_TYPE:
-		parserVal.St = tok.Literal
 		return FLOAT_TYPE
 	case token.INT_TYPE:
-		parserVal.St = tok.Literal
 		return INT_TYPE
 	case token.IF:
-		parserVal.St = tok.Literal
 		return IF
 	case token.ELSE:
-		parserVal.St = tok.Literal
 		return ELSE
+	case token.WHILE:
+		return WHILE
+	case token.FOR:
+		return FOR
+	case token.TO:
+		return TO
+	case token.STEP:
+		return STEP
 	case token.PROGRAM:
-		parserVal.St = tok.Literal
 		return PROGRAM
 	case token.PRINT:
-		parserVal.St = tok.Literal
 		return PRINT
+	case token.BOOL_TYPE:
+		return BOOL_TYPE
+	case token.STRING_TYPE:
+		return STRING_TYPE
+	case token.TRUE:
+		return TRUE
+	case token.FALSE:
+		return FALSE
+	case token.AND:
+		return AND
+	case token.OR:
+		return OR
+	case token.NOT:
+		return '!'
+	case token.MODULO:
+		return '%'
+	case token.BIT_AND:
+		return '&'
+	case token.BIT_OR:
+		return '|'
+	case token.BIT_XOR:
+		return '^'
+	case token.BIT_NOT:
+		return '~'
+	case token.SHIFT_LEFT:
+		return SHIFT_LEFT
+	case token.SHIFT_RIGHT:
+		return SHIFT_RIGHT
+	case token.FUNC:
+		return FUNC
+	case token.RETURN:
+		return RETURN
 	case token.COLON:
-		parserVal.St = tok.Literal
 		return ':'
 	case token.COMMA:
-		parserVal.St = tok.Literal
 		return ','
 	case token.OPEN_BRACE:
-		parserVal.St = tok.Literal
 		return '{'
 	case token.PLUS:
-		parserVal.St = tok.Literal
 		return '+'
 	case token.MINUS:
-		parserVal.St = tok.Literal
 		return '-'
 	case token.DIVIDE:
-		parserVal.St = tok.Literal
 		return '/'
 	case token.MULTIPLY:
-		parserVal.St = tok.Literal
 		return '*'
 	case token.CLOSED_BRACE:
-		parserVal.St = tok.Literal
 		return '}'
+	case token.OPEN_BRACKET:
+		return '['
+	case token.CLOSED_BRACKET:
+		return ']'
 	case token.ASSIGN:
-		parserVal.St = tok.Literal
 		return '='
 	case token.OPEN_PARENTHESIS:
-		parserVal.St = tok.Literal
 		return '('
 	case token.CLOSED_PARENTHESIS:
-		parserVal.St = tok.Literal
 		return ')'
 	case token.INT:
-		parserVal.St = tok.Literal
 		return CTE_I
 	case token.SEMICOLON:
-		parserVal.St = tok.Literal
 		return ';'
 	case token.LESS_THAN:
-		parserVal.St = tok.Literal
 		return '<'
 	case token.GREATER_THAN:
-		parserVal.St = tok.Literal
 		return '>'
+	case token.EQUAL:
+		return EQUAL
+	case token.NOT_EQUAL:
+		return NOT_EQUAL
+	case token.LESS_EQUAL:
+		return LESS_EQUAL
+	case token.GREATER_EQUAL:
+		return GREATER_EQUAL
+	case token.LESS_THEN_GREAT:
+		return LESS_THEN_GREAT
 	case token.STRING:
-		parserVal.St = tok.Literal
 		return CTE_STRING
 	case token.FLOAT:
-		parserVal.St = tok.Literal
 		return CTE_F
+	case token.EOF:
+		return 0
 	default:
-		return int(tok.LineNumber)
+		return ILLEGAL
 	}
 
 }
@@ -1,10 +1,93 @@
 package goyacc
 
-import "ciri/src/token"
+import (
+	"ciri/src/ast"
+	"ciri/src/token"
+	"strings"
+)
 
-// Parse parses the input and returns the result.
-func Parse(input string) ([]token.Token, error) {
+func init() {
+	yyErrorVerbose = true
+}
+
+// Parse parses the input and returns the program tree, the error is a
+// diag.DiagnosticList holding every problem found.
+func Parse(input string) (*ast.Program, error) {
 	l := New(input)
 	_ = yyParse(l)
-	return l.Tokens, l.GetError()
+	return l.program, l.GetError()
+}
+
+// tokenNames spells the grammar symbols the way users know them, the
+// single character ones are already quoted by goyacc
+var tokenNames = map[string]string{
+	"$end":            "end of file",
+	"$unk":            "unknown token",
+	"ILLEGAL":         "illegal token",
+	"ID":              "identifier",
+	"CTE_I":           "integer",
+	"CTE_F":           "float",
+	"CTE_STRING":      "string",
+	"VAR":             "'var'",
+	"IF":              "'if'",
+	"ELSE":            "'else'",
+	"WHILE":           "'while'",
+	"FOR":             "'for'",
+	"TO":              "'to'",
+	"STEP":            "'step'",
+	"PROGRAM":         "'program'",
+	"PRINT":           "'print'",
+	"FUNC":            "'func'",
+	"RETURN":          "'return'",
+	"INT_TYPE":        "'int'",
+	"FLOAT_TYPE":      "'float'",
+	"BOOL_TYPE":       "'bool'",
+	"STRING_TYPE":     "'string'",
+	"TRUE":            "'true'",
+	"FALSE":           "'false'",
+	"AND":             "'&&'",
+	"OR":              "'||'",
+	"EQUAL":           "'=='",
+	"NOT_EQUAL":       "'!='",
+	"LESS_EQUAL":      "'<='",
+	"GREATER_EQUAL":   "'>='",
+	"LESS_THEN_GREAT": "'<>'",
+	"SHIFT_LEFT":      "'<<'",
+	"SHIFT_RIGHT":     "'>>'",
+}
+
+func tokenName(symbol string) string {
+	if name, ok := tokenNames[symbol]; ok {
+		return name
+	}
+	return symbol
+}
+
+// syntaxMessage rewrites a goyacc error like "syntax error: unexpected
+// CTE_I, expecting ';'" with the names of tok, the unexpected token, and
+// of the expected symbols
+func syntaxMessage(s string, tok token.Token) string {
+	s = strings.TrimPrefix(s, "syntax error: ")
+	rest := strings.TrimPrefix(s, "unexpected ")
+	if rest == s {
+		return s
+	}
+
+	unexpected, expecting := rest, ""
+	if i := strings.Index(rest, ", expecting "); i >= 0 {
+		unexpected, expecting = rest[:i], rest[i+len(", expecting "):]
+	}
+
+	message := "unexpected " + tokenName(unexpected)
+	if unexpected == "ID" {
+		message += " " + tok.Literal
+	}
+	if expecting != "" {
+		symbols := strings.Split(expecting, " or ")
+		for i, symbol := range symbols {
+			symbols[i] = tokenName(symbol)
+		}
+		message += ", expecting " + strings.Join(symbols, " or ")
+	}
+	return message
 }
@@ -2,43 +2,136 @@
 package goyacc
 
 import (
+	"ciri/src/ast"
 	"ciri/src/token"
 )
 
-func setResult(l yyLexer, v []token.Token) {
-  l.(*Lexer).Tokens = v
+func setResult(l yyLexer, v *ast.Program) {
+  l.(*Lexer).program = v
+}
+
+func newIdent(tok token.Token) *ast.Ident {
+  return &ast.Ident{Token: tok, Name: tok.Literal}
+}
+
+// declName is a declared name with the dimensions of arrays
+type declName struct {
+  ident *ast.Ident
+  dims  []ast.Expression
+}
+
+func newVarDecl(names []declName, typ token.Token) *ast.VarDecl {
+  decl := &ast.VarDecl{Token: names[0].ident.Token, Type: typ}
+  for _, n := range names {
+    decl.Names = append(decl.Names, n.ident)
+    decl.Dims = append(decl.Dims, n.dims)
+  }
+  return decl
+}
+
+// newInitDecl declares a single variable with an initial value, its type
+// is inferred when typ is the zero token
+func newInitDecl(name token.Token, typ token.Token, value ast.Expression) *ast.VarDecl {
+  return &ast.VarDecl{Token: name, Names: []*ast.Ident{newIdent(name)}, Dims: [][]ast.Expression{nil}, Type: typ, Value: value}
+}
+
+// newTypedInitDecl keeps every name so the checker can report initializing
+// several names or an array
+func newTypedInitDecl(names []declName, typ token.Token, value ast.Expression) *ast.VarDecl {
+  decl := newVarDecl(names, typ)
+  decl.Value = value
+  return decl
+}
+
+// newFunction keeps the declarations that open the body of a function as
+// its local variables, later ones are scoped to the body
+func newFunction(tok token.Token, name *ast.Ident, params []*ast.Param, result token.Token, body *ast.Block) *ast.Function {
+  f := &ast.Function{Token: tok, Name: name, Params: params, Result: result, Body: body}
+  for len(body.Statements) > 0 {
+    decl, ok := body.Statements[0].(*ast.DeclStmt)
+    if !ok {
+      break
+    }
+    f.Vars = append(f.Vars, decl.Decl)
+    body.Statements = body.Statements[1:]
+  }
+  return f
+}
+
+func newBinary(op token.Token, left, right ast.Expression) *ast.BinaryExpr {
+  return &ast.BinaryExpr{Token: op, Operator: op.Literal, Left: left, Right: right}
 }
 %}
 
 %union{
-  St string
-  Fl float64
-  In int
-  Ch byte
+  Tok   token.Token
+  Prog  *ast.Program
+  Decls []*ast.VarDecl
+  Funcs []*ast.Function
+  Func  *ast.Function
+  Params []*ast.Param
+  Names []declName
+  Block *ast.Block
+  Stmt  ast.Statement
+  Stmts []ast.Statement
+  Expr  ast.Expression
+  Exprs []ast.Expression
 }
 
-%token<Fl>
+%token<Tok>
         CTE_F
-%token<In>
 	CTE_I
-
-%token<St>
 	VAR
 	IF
 	ELSE
+	WHILE
+	FOR
+	TO
+	STEP
 	ID
 	CTE_STRING
 
 	INT_TYPE
 	FLOAT_TYPE
+	BOOL_TYPE
+	STRING_TYPE
+	TRUE
+	FALSE
+	AND
+	OR
+	EQUAL
+	NOT_EQUAL
+	LESS_EQUAL
+	GREATER_EQUAL
+	LESS_THEN_GREAT
+	SHIFT_LEFT
+	SHIFT_RIGHT
 
 	PROGRAM
 	PRINT
+	FUNC
+	RETURN
 
-%left '|'
-%left '&'
-%left '+'  '-'
-%left '*'  '/'  '%'
+	ILLEGAL /* tokens the grammar does not use */
+
+%token<Tok> '+' '-' '*' '/' '%' '&' '|' '^' '~' '<' '>' '!' '{' '}' '(' ')' '[' ']' '=' ';' ':' ','
+
+%type<Decls> vars varDecl allVars nextVar
+%type<Names> nextId
+%type<Funcs> funcs
+%type<Func>  function
+%type<Params> params nextParam
+%type<Block> bloque elseBlock
+%type<Stmts> nextStatuto declRest declVars stmtRest
+%type<Stmt>  estatuto condition loop forLoop assign print return
+%type<Exprs> nextPrint args nextArg indices
+%type<Expr>  target call forStep varCte factor termino exp expresion orExp andExp nextExp
+%type<Tok>   tipo result
+
+%left OR
+%left AND
+%left '+'  '-'  '|'  '^'
+%left '*'  '/'  '%'  '&'  SHIFT_LEFT  SHIFT_RIGHT
 %left UMINUS      /*  supplies  precedence  for  unary  minus  */
 
 
@@ -46,66 +139,225 @@ func setResult(l yyLexer, v []token.Token) {
 
 %%
 
-programa: PROGRAM ID ':' vars bloque
+programa: PROGRAM ID ':' vars funcs bloque
+	{
+		setResult(yylex, &ast.Program{Token: $1, Name: newIdent($2), Vars: $4, Funcs: $5, Body: $6})
+	}
 
-vars: VAR allVars
+vars: VAR varDecl
+	{ $$ = $2 }
     |
+	{ $$ = nil }
+varDecl: ID '=' expresion ';' nextVar
+	{ $$ = append([]*ast.VarDecl{newInitDecl($1, token.Token{}, $3)}, $5...) }
+       | allVars
 allVars: nextId ':' tipo ';' nextVar
+	{ $$ = append([]*ast.VarDecl{newVarDecl($1, $3)}, $5...) }
+       | nextId ':' tipo '=' expresion ';' nextVar
+	{ $$ = append([]*ast.VarDecl{newTypedInitDecl($1, $3, $5)}, $7...) }
+       | error ';' nextVar
+	{ $$ = $3 }
 nextId: ID
+	{ $$ = []declName{{ident: newIdent($1)}} }
+      | ID indices
+	{ $$ = []declName{{ident: newIdent($1), dims: $2}} }
       | ID ',' nextId
+	{ $$ = append([]declName{{ident: newIdent($1)}}, $3...) }
+      | ID indices ',' nextId
+	{ $$ = append([]declName{{ident: newIdent($1), dims: $2}}, $4...) }
+indices: '[' expresion ']'
+	{ $$ = []ast.Expression{$2} }
+       | '[' expresion ']' indices
+	{ $$ = append([]ast.Expression{$2}, $4...) }
 nextVar: allVars
+       | VAR varDecl
+	{ $$ = $2 }
        |
+	{ $$ = nil }
+
+funcs: function funcs
+	{ $$ = append([]*ast.Function{$1}, $2...) }
+     |
+	{ $$ = nil }
+function: FUNC ID '(' params ')' result '{' nextStatuto '}'
+	{ $$ = newFunction($1, newIdent($2), $4, $6, &ast.Block{Token: $7, Statements: $8}) }
+params: ID ':' tipo nextParam
+	{ $$ = append([]*ast.Param{{Name: newIdent($1), Type: $3}}, $4...) }
+      |
+	{ $$ = nil }
+nextParam: ',' ID ':' tipo nextParam
+	{ $$ = append([]*ast.Param{{Name: newIdent($2), Type: $4}}, $5...) }
+	 |
+	{ $$ = nil }
+result: ':' tipo
+	{ $$ = $2 }
+      |
+	{ $$ = token.Token{} }
 
 
 bloque: '{' nextStatuto '}'
+	{ $$ = &ast.Block{Token: $1, Statements: $2} }
+      | '{' error '}'
+	{ $$ = &ast.Block{Token: $1, Statements: []ast.Statement{&ast.BadStmt{Token: $1}}} }
+/* declarations and statements are parsed together so an ID can start
+   either of them */
 nextStatuto: estatuto nextStatuto
+	{ $$ = append([]ast.Statement{$1}, $2...) }
+	   | VAR declRest
+	{ $$ = $2 }
 	   |
+	{ $$ = nil }
+declRest: ID '=' expresion ';' stmtRest
+	{ $$ = append([]ast.Statement{&ast.DeclStmt{Decl: newInitDecl($1, token.Token{}, $3)}}, $5...) }
+	| declVars
+declVars: nextId ':' tipo ';' stmtRest
+	{ $$ = append([]ast.Statement{&ast.DeclStmt{Decl: newVarDecl($1, $3)}}, $5...) }
+	| nextId ':' tipo '=' expresion ';' stmtRest
+	{ $$ = append([]ast.Statement{&ast.DeclStmt{Decl: newTypedInitDecl($1, $3, $5)}}, $7...) }
+stmtRest: declVars
+	| nextStatuto
 
 estatuto: assign
 	| condition
+	| loop
+	| forLoop
 	| print
+	| return
+	| call ';'
+	{ $$ = &ast.CallStmt{Call: $1.(*ast.Call)} }
+	| error ';'
+	{ $$ = &ast.BadStmt{Token: $2} }
 
 
 condition: IF '(' expresion ')' bloque elseBlock ';'
+	{ $$ = &ast.If{Token: $1, Condition: $3, Consequence: $5, Alternative: $6} }
+	 | IF '(' error ')' bloque elseBlock ';'
+	{ $$ = &ast.BadStmt{Token: $1} }
 elseBlock: ELSE bloque
+	{ $$ = $2 }
 	 |
+	{ $$ = nil }
+
+loop: WHILE '(' expresion ')' bloque ';'
+	{ $$ = &ast.While{Token: $1, Condition: $3, Body: $5} }
+    | WHILE '(' error ')' bloque ';'
+	{ $$ = &ast.BadStmt{Token: $1} }
+
+forLoop: FOR ID '=' expresion TO expresion forStep bloque ';'
+	{ $$ = &ast.For{Token: $1, Var: newIdent($2), Start: $4, End: $6, Step: $7, Body: $8} }
+forStep: STEP expresion
+	{ $$ = $2 }
+       |
+	{ $$ = nil }
 
-assign: ID '=' expresion ';'
+return: RETURN expresion ';'
+	{ $$ = &ast.Return{Token: $1, Value: $2} }
+      | RETURN ';'
+	{ $$ = &ast.Return{Token: $1} }
 
-print: PRINT '(' nextPrintExp nextPrint ')' ';'
-nextPrintExp: expresion
-	   |  CTE_STRING ;
-nextPrint: ',' nextPrintExp nextPrint
+assign: target '=' expresion ';'
+	{ $$ = &ast.Assign{Token: $1.Pos(), Target: $1, Value: $3} }
+target: ID
+	{ $$ = newIdent($1) }
+      | ID indices
+	{ $$ = &ast.Index{Token: $1, Array: newIdent($1), Indices: $2} }
+
+print: PRINT '(' expresion nextPrint ')' ';'
+	{ $$ = &ast.Print{Token: $1, Args: append([]ast.Expression{$3}, $4...)} }
+nextPrint: ',' expresion nextPrint
+	{ $$ = append([]ast.Expression{$2}, $3...) }
 	 |
+	{ $$ = nil }
 
 tipo: INT_TYPE
     | FLOAT_TYPE
+    | BOOL_TYPE
+    | STRING_TYPE
 
 varCte: ID
+	{ $$ = newIdent($1) }
+       | ID indices
+	{ $$ = &ast.Index{Token: $1, Array: newIdent($1), Indices: $2} }
+       | call
        | CTE_I
+	{ $$ = &ast.Literal{Token: $1} }
        | CTE_F
+	{ $$ = &ast.Literal{Token: $1} }
+       | TRUE
+	{ $$ = &ast.Literal{Token: $1} }
+       | FALSE
+	{ $$ = &ast.Literal{Token: $1} }
+       | CTE_STRING
+	{ $$ = &ast.Literal{Token: $1} }
 
-factor: '(' expresion ')'
-      | cteExp
-cteExp: varCte
-      | '+' varCte
-      | '-' varCte
+call: ID '(' args ')'
+	{ $$ = &ast.Call{Token: $1, Function: newIdent($1), Args: $3} }
+args: expresion nextArg
+	{ $$ = append([]ast.Expression{$1}, $2...) }
+    |
+	{ $$ = nil }
+nextArg: ',' expresion nextArg
+	{ $$ = append([]ast.Expression{$2}, $3...) }
+       |
+	{ $$ = nil }
 
-termino: nextFactor
+factor: '(' expresion ')'
+	{ $$ = $2 }
+      | '!' factor
+	{ $$ = &ast.UnaryExpr{Token: $1, Operator: $1.Literal, Operand: $2} }
+      | '~' factor
+	{ $$ = &ast.UnaryExpr{Token: $1, Operator: $1.Literal, Operand: $2} }
+      | '+' factor %prec UMINUS
+	{ $$ = &ast.UnaryExpr{Token: $1, Operator: $1.Literal, Operand: $2} }
+      | '-' factor %prec UMINUS
+	{ $$ = &ast.UnaryExpr{Token: $1, Operator: $1.Literal, Operand: $2} }
+      | varCte
 
-nextFactor: factor
-	  | factor '/' termino
-	  | factor '*' termino
+termino: termino '*' factor
+	{ $$ = newBinary($2, $1, $3) }
+       | termino '/' factor
+	{ $$ = newBinary($2, $1, $3) }
+       | termino '%' factor
+	{ $$ = newBinary($2, $1, $3) }
+       | termino '&' factor
+	{ $$ = newBinary($2, $1, $3) }
+       | termino SHIFT_LEFT factor
+	{ $$ = newBinary($2, $1, $3) }
+       | termino SHIFT_RIGHT factor
+	{ $$ = newBinary($2, $1, $3) }
+       | factor
 
-exp: termino nextTerm
+exp: exp '+' termino
+	{ $$ = newBinary($2, $1, $3) }
+   | exp '-' termino
+	{ $$ = newBinary($2, $1, $3) }
+   | exp '|' termino
+	{ $$ = newBinary($2, $1, $3) }
+   | exp '^' termino
+	{ $$ = newBinary($2, $1, $3) }
+   | termino
 
-nextTerm: '+' termino nextTerm
-	 | '-' termino nextTerm
-	 |
+expresion: orExp
 
-expresion: nextExp
+orExp: orExp OR andExp
+	{ $$ = newBinary($2, $1, $3) }
+     | andExp
+andExp: andExp AND nextExp
+	{ $$ = newBinary($2, $1, $3) }
+      | nextExp
 
 nextExp: exp '>' exp
+	{ $$ = newBinary($2, $1, $3) }
        | exp '<' exp
+	{ $$ = newBinary($2, $1, $3) }
+       | exp LESS_EQUAL exp
+	{ $$ = newBinary($2, $1, $3) }
+       | exp GREATER_EQUAL exp
+	{ $$ = newBinary($2, $1, $3) }
+       | exp EQUAL exp
+	{ $$ = newBinary($2, $1, $3) }
+       | exp NOT_EQUAL exp
+	{ $$ = newBinary($2, $1, $3) }
+       | exp LESS_THEN_GREAT exp
+	{ $$ = newBinary($2, $1, $3) }
 	   | exp
-
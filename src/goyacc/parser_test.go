@@ -1,6 +1,11 @@
 package goyacc
 
-import "testing"
+import (
+	"ciri/src/ast"
+	"ciri/src/diag"
+	"strings"
+	"testing"
+)
 
 //Program structure
 
@@ -199,3 +204,724 @@ func TestThrowError(t *testing.T) {
 		t.Fatalf("should not compile")
 	}
 }
+
+// Tree
+
+func TestParseBuildsProgram(t *testing.T) {
+	input := `
+		program testRun : var x, y: int; z: float; {
+			x = 10;
+			if (x > 1) {
+				print("big", x);
+			} else {
+				z = -y;
+			};
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if program.Name.Name != "testRun" {
+		t.Fatalf("program name wrong. expected=%q, got=%q", "testRun", program.Name.Name)
+	}
+
+	if len(program.Vars) != 2 {
+		t.Fatalf("expected 2 var declarations, got=%d", len(program.Vars))
+	}
+
+	expected := `program testRun: var x, y: int; z: float; { x = 10; if ((x > 1)) { print("big", x); } else { z = (-y); }; }`
+	if program.String() != expected {
+		t.Fatalf("tree wrong.\nexpected=%s\ngot=     %s", expected, program.String())
+	}
+}
+
+func TestParseExpressionTree(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1 + 2 + 3;", "x = ((1 + 2) + 3);"},
+		{"x = 1 - 2 * 3;", "x = (1 - (2 * 3));"},
+		{"x = (1 - 2) * 3;", "x = ((1 - 2) * 3);"},
+		{"x = a + b > c;", "x = ((a + b) > c);"},
+		{"x = 8 / 4 / 2;", "x = ((8 / 4) / 2);"},
+		{"x = 8 - 4 - 2;", "x = ((8 - 4) - 2);"},
+		{"x = 2 * 3 / 4 % 5;", "x = (((2 * 3) / 4) % 5);"},
+		{"x = 100 % 7 * 2;", "x = ((100 % 7) * 2);"},
+		{"x = 1 - 2 + 3;", "x = ((1 - 2) + 3);"},
+		{"x = a / b * c - d / e;", "x = (((a / b) * c) - (d / e));"},
+		{"x = 1 << 2 << 3;", "x = ((1 << 2) << 3);"},
+		{"x = a >> 4 & 15;", "x = ((a >> 4) & 15);"},
+		{"x = a - b == c * d;", "x = ((a - b) == (c * d));"},
+	}
+
+	for i, tt := range tests {
+		program, err := Parse("program p : { " + tt.input + " }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err.Error())
+		}
+
+		stmt := program.Body.Statements[0]
+		if stmt.String() != tt.expected {
+			t.Fatalf("tests[%d] - tree wrong. expected=%q, got=%q", i, tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestParseNonAssociativeComparisons(t *testing.T) {
+	inputs := []string{
+		"x = a < b < c;",
+		"x = a == b == c;",
+		"x = a <= b > c;",
+		"x = a <> b != c;",
+	}
+
+	for i, input := range inputs {
+		if _, err := Parse("program p : { " + input + " }"); err == nil {
+			t.Fatalf("inputs[%d] - %q should not compile", i, input)
+		}
+	}
+	if _, err := Parse("program p : { x = (a < b) == c; }"); err != nil {
+		t.Fatalf("parenthesized comparison should compile: %s", err)
+	}
+}
+
+func TestParseTokenPositions(t *testing.T) {
+	input := "program p : {\n x = 10;\n}"
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assign, ok := program.Body.Statements[0].(*ast.Assign)
+	if !ok {
+		t.Fatalf("expected *ast.Assign, got=%T", program.Body.Statements[0])
+	}
+
+	pos := assign.Pos()
+	if pos.Line != 2 || pos.Column != 2 || pos.Offset != 15 {
+		t.Fatalf("position wrong. expected=2:2 @15, got=%d:%d @%d", pos.Line, pos.Column, pos.Offset)
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	input := `
+		program testRun : {
+			x = 10 @ 1;
+		}
+	`
+	_, err := Parse(input)
+
+	diagnostics, ok := err.(diag.DiagnosticList)
+	if !ok {
+		t.Fatalf("expected diag.DiagnosticList, got=%T", err)
+	}
+
+	if len(diagnostics.Filter(diag.IllegalToken)) != 1 {
+		t.Fatalf("expected an illegal token diagnostic, got=%s", diagnostics)
+	}
+
+	syntax := diagnostics.Filter(diag.Syntax)
+	if len(syntax) != 1 {
+		t.Fatalf("expected a syntax diagnostic, got=%s", diagnostics)
+	}
+	if syntax[0].Pos.Line != 3 || syntax[0].Message != "unexpected integer, expecting ';'" {
+		t.Fatalf("syntax diagnostic wrong, got=%s", syntax[0])
+	}
+}
+
+// Error recovery
+
+func TestParseReportsEverySyntaxError(t *testing.T) {
+	input := `program test: var x, d : int; y int; z: float; {
+    x = 10 +;
+    x = 20 + 50 + ty;
+
+    if (x <== 10) {
+        print("hello");
+    };
+
+    print("hello" x);
+    z = 1.5;
+}`
+	program, err := Parse(input)
+	if err == nil {
+		t.Fatalf("should not compile")
+	}
+
+	diagnostics := err.(diag.DiagnosticList)
+	expectedLines := []uint32{1, 2, 5, 9}
+	if len(diagnostics) != len(expectedLines) {
+		t.Fatalf("expected %d errors, got=%d\n%s", len(expectedLines), len(diagnostics), err)
+	}
+	for i, line := range expectedLines {
+		if diagnostics[i].Pos.Line != line {
+			t.Fatalf("errors[%d] - line wrong. expected=%d, got=%d", i, line, diagnostics[i].Pos.Line)
+		}
+	}
+
+	if program == nil {
+		t.Fatalf("expected a partial program")
+	}
+	last := program.Body.Statements[len(program.Body.Statements)-1]
+	if last.String() != "z = 1.5;" {
+		t.Fatalf("statements after the errors should be parsed, got=%q", last)
+	}
+}
+
+func TestParseSyntaxMessages(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"program p: { x = = 2; }", "unexpected '='"},
+		{"program p: { x = 1 }", "unexpected '}', expecting ';'"},
+		{"program p: var x int; {}", "unexpected 'int', expecting ':'"},
+		{"program p: var x: ; {}", "unexpected ';', expecting 'int' or 'float' or 'bool' or 'string'"},
+		{"program p: { print(1) x = 2; }", "unexpected identifier x"},
+		{"program p: { x = 1;", "unexpected end of file"},
+		{"program p: { x = 1 2; }", "unexpected integer"},
+	}
+
+	for i, tt := range tests {
+		_, err := Parse(tt.input)
+		diagnostics, ok := err.(diag.DiagnosticList)
+		if !ok || len(diagnostics) == 0 {
+			t.Fatalf("tests[%d] - expected diagnostics, got=%v", i, err)
+		}
+		if !strings.HasPrefix(diagnostics[0].Message, tt.expected) {
+			t.Fatalf("tests[%d] - message wrong. expected=%q, got=%q", i, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestParseRecoversInsideBlocks(t *testing.T) {
+	input := `
+		program testRun : var x: int; {
+			if (x > 1) {
+				x = = 2;
+				x = 3;
+			} else {
+				print(x;
+			};
+			print(x);
+		}
+	`
+	program, err := Parse(input)
+	if err == nil {
+		t.Fatalf("should not compile")
+	}
+
+	if n := len(err.(diag.DiagnosticList)); n != 2 {
+		t.Fatalf("expected 2 errors, got=%d\n%s", n, err)
+	}
+
+	expected := `program testRun: var x: int; { if ((x > 1)) { <bad statement>; x = 3; } else { <bad statement>; }; print(x); }`
+	if program.String() != expected {
+		t.Fatalf("tree wrong.\nexpected=%s\ngot=     %s", expected, program.String())
+	}
+}
+
+func TestParseRecoversInDeclarations(t *testing.T) {
+	input := `
+		program testRun : var x: int; y float; z, : int; w: float; {
+			w = 1.5;
+		}
+	`
+	program, err := Parse(input)
+	if err == nil {
+		t.Fatalf("should not compile")
+	}
+
+	if n := len(err.(diag.DiagnosticList)); n != 2 {
+		t.Fatalf("expected 2 errors, got=%d\n%s", n, err)
+	}
+
+	if len(program.Vars) != 2 || program.Vars[1].Names[0].Name != "w" {
+		t.Fatalf("declarations after the errors should be parsed, got=%v", program.Vars)
+	}
+}
+
+// Loops
+
+func TestParseWhile(t *testing.T) {
+	input := `
+		program testRun : var x: int; {
+			while (x < 10) {
+				x = x + 1;
+				while (x > 100) {};
+			};
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	loop, ok := program.Body.Statements[0].(*ast.While)
+	if !ok {
+		t.Fatalf("expected *ast.While, got=%T", program.Body.Statements[0])
+	}
+	if loop.String() != "while ((x < 10)) { x = (x + 1); while ((x > 100)) { }; };" {
+		t.Fatalf("tree wrong, got=%q", loop.String())
+	}
+}
+
+func TestParseFor(t *testing.T) {
+	input := `
+		program testRun : var i, j: int; {
+			for i = 0 to 10 step 2 {
+				for j = i to 0 step -1 {};
+			};
+			for i = 1 to 3 {};
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tests := []string{
+		"for i = 0 to 10 step 2 { for j = i to 0 step (-1) { }; };",
+		"for i = 1 to 3 { };",
+	}
+	for i, expected := range tests {
+		loop, ok := program.Body.Statements[i].(*ast.For)
+		if !ok {
+			t.Fatalf("statements[%d] - expected *ast.For, got=%T", i, program.Body.Statements[i])
+		}
+		if loop.String() != expected {
+			t.Fatalf("statements[%d] - tree wrong. expected=%q, got=%q", i, expected, loop.String())
+		}
+	}
+
+	if program.Body.Statements[1].(*ast.For).Step != nil {
+		t.Fatalf("omitted step should be nil")
+	}
+}
+
+func TestParseForMissingTo(t *testing.T) {
+	input := `
+		program testRun : var i: int; {
+			for i = 0, 10 {};
+		}
+	`
+	_, err := Parse(input)
+	if err == nil {
+		t.Fatalf("should not compile")
+	}
+}
+
+func TestParseWhileMissingSemicolon(t *testing.T) {
+	input := `
+		program testRun : {
+			while (x < 10) {}
+		}
+	`
+	_, err := Parse(input)
+	if err == nil {
+		t.Fatalf("should not compile")
+	}
+}
+
+func TestParseUnaryMinus(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a - -b", "(a - (-b))"},
+		{"-x * y", "((-x) * y)"},
+		{"-(a + b)", "(-(a + b))"},
+		{"-f(x) + 1", "((-f(x)) + 1)"},
+		{"- -a", "(-(-a))"},
+		{"+a - +2", "((+a) - (+2))"},
+		{"-g[1] % 3", "((-g[1]) % 3)"},
+	}
+
+	for i, tt := range tests {
+		program, err := Parse("program p: var x: int; { x = " + tt.input + "; }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+		assign := program.Body.Statements[0].(*ast.Assign)
+		if assign.Value.String() != tt.expected {
+			t.Fatalf("tests[%d] - tree wrong. expected=%q, got=%q", i, tt.expected, assign.Value.String())
+		}
+	}
+}
+
+// Booleans
+
+func TestParseLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c", "((a && b) || c)"},
+		{"a || b || c", "((a || b) || c)"},
+		{"x > 1 && !done", "((x > 1) && (!done))"},
+		{"!(a || b) && true", "((!(a || b)) && true)"},
+		{"!!false", "(!(!false))"},
+	}
+
+	for i, tt := range tests {
+		program, err := Parse("program p: var ok: bool; { ok = " + tt.input + "; }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+		assign := program.Body.Statements[0].(*ast.Assign)
+		if assign.Value.String() != tt.expected {
+			t.Fatalf("tests[%d] - tree wrong. expected=%q, got=%q", i, tt.expected, assign.Value.String())
+		}
+	}
+}
+
+func TestParseComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a == b", "(a == b)"},
+		{"a != b + 1", "(a != (b + 1))"},
+		{"a <= b", "(a <= b)"},
+		{"a * 2 >= b", "((a * 2) >= b)"},
+		{"a <> b", "(a <> b)"},
+		{"a == b && c <> d", "((a == b) && (c <> d))"},
+	}
+
+	for i, tt := range tests {
+		program, err := Parse("program p: var ok: bool; { ok = " + tt.input + "; }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+		assign := program.Body.Statements[0].(*ast.Assign)
+		if assign.Value.String() != tt.expected {
+			t.Fatalf("tests[%d] - tree wrong. expected=%q, got=%q", i, tt.expected, assign.Value.String())
+		}
+	}
+
+	if _, err := Parse("program p: { if (x <== 10) {}; }"); err == nil {
+		t.Fatalf("<== should not compile")
+	}
+}
+
+func TestParseBitwise(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a % 16", "(a % 16)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a ^ b << 2", "(a ^ (b << 2))"},
+		{"a | b ^ c", "((a | b) ^ c)"},
+		{"a >> 4 | b", "((a >> 4) | b)"},
+		{"~a & 255", "((~a) & 255)"},
+		{"~(a | b)", "(~(a | b))"},
+	}
+
+	for i, tt := range tests {
+		program, err := Parse("program p: var x: int; { x = " + tt.input + "; }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+		assign := program.Body.Statements[0].(*ast.Assign)
+		if assign.Value.String() != tt.expected {
+			t.Fatalf("tests[%d] - tree wrong. expected=%q, got=%q", i, tt.expected, assign.Value.String())
+		}
+	}
+
+	if program, err := Parse("program p: var ok: bool; { ok = a & 1 == 0; }"); err != nil {
+		t.Fatalf(err.Error())
+	} else if got := program.Body.Statements[0].(*ast.Assign).Value.String(); got != "((a & 1) == 0)" {
+		t.Fatalf("masks should bind tighter than comparisons, got=%q", got)
+	}
+}
+
+// Strings
+
+func TestParseStrings(t *testing.T) {
+	input := `
+		program p: var msg, cmds[2]: string; n: int;
+		func greet(name: string): string {
+			return "hi " + name;
+		}
+		{
+			msg = "id=" + n + ";";
+			cmds[0] = greet(msg);
+			n = len(msg) + 1;
+			if (msg == "reset") {
+				print("len", len(cmds[0]));
+			};
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `program p: var msg, cmds[2]: string; n: int; ` +
+		`func greet(name: string): string { return ("hi " + name); } ` +
+		`{ msg = (("id=" + n) + ";"); cmds[0] = greet(msg); n = (len(msg) + 1); ` +
+		`if ((msg == "reset")) { print("len", len(cmds[0])); }; }`
+	if program.String() != expected {
+		t.Fatalf("program wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+}
+
+func TestParseInvalidEscape(t *testing.T) {
+	_, err := Parse("program p: {\n  print(\"bad \\q\");\n}")
+	expected := `2:14: error: unknown escape sequence \q`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error wrong. expected=%q, got=%v", expected, err)
+	}
+}
+
+func TestParseUnterminatedString(t *testing.T) {
+	_, err := Parse("program p: {\n  print(\"unterminated);\n}\n")
+	expected := `2:9: error: string not terminated`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error wrong. expected=%q, got=%v", expected, err)
+	}
+}
+
+// Comments
+
+func TestParseComments(t *testing.T) {
+	input := `
+		// blink the status led
+		program blink: var on: bool; /* state */ n: int;
+		/*
+		func unused() { /* nested */ }
+		*/
+		{
+			n = 10 / /* never zero */ 2; // five
+			print(n);
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := "program blink: var on: bool; n: int; { n = (10 / 2); print(n); }"
+	if program.String() != expected {
+		t.Fatalf("program wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+
+	_, err = Parse("program p: {\n} /* no end")
+	if err == nil || !strings.Contains(err.Error(), "2:3: error: comment not terminated") {
+		t.Fatalf("expected an unterminated comment error, got=%v", err)
+	}
+}
+
+func TestParseMalformedNumber(t *testing.T) {
+	_, err := Parse("program p: var f: float; {\n  f = 1.2.3;\n}")
+	expected := `2:7: error: illegal token "1.2.3"`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("error wrong. expected=%q, got=%v", expected, err)
+	}
+}
+
+// Initialized declarations
+
+func TestParseInitializedVars(t *testing.T) {
+	input := `
+		program p: var x = 10; y: float = 2; a, b: int;
+		var name = "dev" + x;
+		var ok: bool = x > 1;
+		func f(n: int): int {
+			var twice = n * 2;
+			var m: float = 1; k: int;
+			k = twice;
+			return k;
+		}
+		{ print(x); }
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `program p: var x = 10; y: float = 2; a, b: int; var name = ("dev" + x); ok: bool = (x > 1); ` +
+		`func f(n: int): int { var twice = (n * 2); m: float = 1; k: int; k = twice; return k; } ` +
+		`{ print(x); }`
+	if program.String() != expected {
+		t.Fatalf("program wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+
+	decl := program.Vars[0]
+	if decl.Type.Type != "" || decl.Value == nil || decl.Value.String() != "10" {
+		t.Fatalf("x should have an inferred type and a value, got=%q", decl.String())
+	}
+
+	if _, err := Parse(expected); err != nil {
+		t.Fatalf("printed program should parse again: %s", err)
+	}
+}
+
+func TestParseInitializedVarErrors(t *testing.T) {
+	tests := []string{
+		`program p: var x: int; y = 1; {}`,
+		`program p: var x = ; {}`,
+		`program p: var x, y = 1; {}`,
+		`program p: var x[2] = 1; {}`,
+		`program p: { if (true) { var x; }; }`,
+	}
+
+	for i, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Fatalf("tests[%d] - should not compile: %s", i, input)
+		}
+	}
+}
+
+// Arrays
+
+func TestParseArrays(t *testing.T) {
+	input := `
+		program testRun : var readings[16]: float; grid[4][4], i: int;
+		func f() {
+			var buf[2]: int;
+			buf[0] = 1;
+		}
+		{
+			grid[i][i + 1] = readings[i] * 2;
+			print(grid[0][3]);
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := "program testRun: var readings[16]: float; grid[4][4], i: int; " +
+		"func f() { var buf[2]: int; buf[0] = 1; } " +
+		"{ grid[i][(i + 1)] = (readings[i] * 2); print(grid[0][3]); }"
+	if program.String() != expected {
+		t.Fatalf("tree wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+
+	assign := program.Body.Statements[0].(*ast.Assign)
+	if _, ok := assign.Target.(*ast.Index); !ok {
+		t.Fatalf("expected *ast.Index target, got=%T", assign.Target)
+	}
+}
+
+func TestParseArrayErrors(t *testing.T) {
+	tests := []string{
+		`program p: var a[]: int; {}`,
+		`program p: var a[4: int; {}`,
+		`program p: { a[1 = 2; }`,
+		`program p: { a[1]] = 2; }`,
+	}
+
+	for i, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Fatalf("tests[%d] - should not compile: %s", i, input)
+		}
+	}
+}
+
+// Functions
+
+func TestParseFunctions(t *testing.T) {
+	input := `
+		program testRun : var x: int;
+		func add(a: int, b: float): float {
+			var total: float; i: int;
+			total = a + b;
+			return total;
+		}
+		func hello() {
+			print("hello");
+			return;
+		}
+		{
+			x = add(1, 2.5) * -add(x, 0.5);
+			hello();
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tests := []string{
+		"func add(a: int, b: float): float { var total: float; i: int; total = (a + b); return total; }",
+		`func hello() { print("hello"); return; }`,
+	}
+	if len(program.Funcs) != len(tests) {
+		t.Fatalf("expected %d functions, got=%d", len(tests), len(program.Funcs))
+	}
+	for i, expected := range tests {
+		if program.Funcs[i].String() != expected {
+			t.Fatalf("funcs[%d] - tree wrong. expected=%q, got=%q", i, expected, program.Funcs[i].String())
+		}
+	}
+
+	expected := "{ x = (add(1, 2.5) * (-add(x, 0.5))); hello(); }"
+	if program.Body.String() != expected {
+		t.Fatalf("body wrong. expected=%q, got=%q", expected, program.Body.String())
+	}
+	if _, ok := program.Body.Statements[1].(*ast.CallStmt); !ok {
+		t.Fatalf("expected *ast.CallStmt, got=%T", program.Body.Statements[1])
+	}
+}
+
+func TestParseFunctionErrors(t *testing.T) {
+	tests := []string{
+		`program p: func f(a int) {} {}`,
+		`program p: func f(a: int): {} {}`,
+		`program p: func f() { x = 1; var; } {}`,
+		`program p: { f(1,); }`,
+		`program p: { return 1 }`,
+	}
+
+	for i, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Fatalf("tests[%d] - should not compile: %s", i, input)
+		}
+	}
+}
+
+func TestParseBlockVars(t *testing.T) {
+	input := `
+		program p: var x = 1;
+		func f(n: int): int {
+			var a = n;
+			a = a + 1;
+			var b: int = a; c: float;
+			return b;
+		}
+		{
+			var y = x;
+			if (y > 0) { var z: int; z = y; print(z); };
+			while (x < 3) { x = x + 1; var w, v: bool; };
+		}
+	`
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `program p: var x = 1; ` +
+		`func f(n: int): int { var a = n; a = (a + 1); var b: int = a; var c: float; return b; } ` +
+		`{ var y = x; if ((y > 0)) { var z: int; z = y; print(z); }; while ((x < 3)) { x = (x + 1); var w, v: bool; }; }`
+	if program.String() != expected {
+		t.Fatalf("program wrong.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+
+	if len(program.Funcs[0].Vars) != 1 {
+		t.Fatalf("leading declarations should stay function vars, got=%d", len(program.Funcs[0].Vars))
+	}
+	if _, ok := program.Body.Statements[0].(*ast.DeclStmt); !ok {
+		t.Fatalf("statements[0] is not *ast.DeclStmt, got=%T", program.Body.Statements[0])
+	}
+
+	if _, err := Parse(expected); err != nil {
+		t.Fatalf("printed program should parse again: %s", err)
+	}
+}
@@ -0,0 +1,567 @@
+package ir
+
+import (
+	"ciri/src/ast"
+	"ciri/src/diag"
+	"ciri/src/semantic"
+	"ciri/src/token"
+	"strconv"
+)
+
+type generator struct {
+	program *Program
+	info    *semantic.Info
+
+	// addrs holds the address of every variable, functions maps names to
+	// their index in program.Functions
+	addrs     map[*semantic.Symbol]Addr
+	functions map[string]int
+	constants map[constantKey]Addr
+
+	// temps is the temp frame of the code being generated, function is the
+	// function it belongs to, nil in the main block. Temps only live for a
+	// statement, tempPeak is the most the frame needs at once.
+	temps    *Size
+	tempPeak Size
+	function *Function
+	// vars is the segment block variables are stacked on, nested blocks
+	// reuse the slots of the blocks that already ended. peak is the most it
+	// grew to.
+	vars    *Size
+	segment Segment
+	peak    Size
+	// pos is the statement being generated, memory errors are reported at it
+	pos token.Token
+
+	// operands holds the addresses of the values being combined,
+	// jumps holds the quadruples waiting for a jump target
+	operands []Addr
+	jumps    []int
+
+	diagnostics diag.DiagnosticList
+}
+
+type constantKey struct {
+	t       semantic.Type
+	literal string
+}
+
+// Generate lowers a checked program into quadruples
+func Generate(program *ast.Program, info *semantic.Info) (*Program, error) {
+	g := &generator{
+		program:   &Program{Name: program.Name.Name},
+		info:      info,
+		addrs:     make(map[*semantic.Symbol]Addr),
+		functions: make(map[string]int),
+		constants: make(map[constantKey]Addr),
+	}
+
+	for _, symbol := range info.Globals.Symbols() {
+		addr := g.alloc(&g.program.GlobalSize, Global, symbol.Type, symbol.Size(), symbol.Token)
+		g.addrs[symbol] = addr
+		g.program.Globals = append(g.program.Globals, Symbol{Name: symbol.Name, Addr: addr})
+	}
+
+	// frames are laid out before any code so calls can refer to functions
+	// declared after them
+	for _, f := range program.Funcs {
+		g.declare(info.Functions[f.Name.Name])
+	}
+
+	g.temps, g.tempPeak = &g.program.TempSize, Size{}
+	g.stack(&g.program.GlobalSize, Global)
+	g.initialize(program.Vars)
+	g.block(program.Body)
+	g.emit(END, NoAddr, NoAddr, NoAddr, program.Body.Token)
+	*g.vars, *g.temps = g.peak, g.tempPeak
+
+	for i, f := range program.Funcs {
+		g.function = &g.program.Functions[i]
+		g.function.Start = g.next()
+		g.temps, g.tempPeak = &g.function.TempSize, Size{}
+		g.stack(&g.function.LocalSize, Local)
+		g.initialize(f.Vars)
+		g.block(f.Body)
+		g.emit(ENDFUNC, NoAddr, NoAddr, NoAddr, f.Token)
+		*g.vars, *g.temps = g.peak, g.tempPeak
+	}
+
+	if err := g.diagnostics.Err(); err != nil {
+		return nil, err
+	}
+	return g.program, nil
+}
+
+// declare assigns local addresses to the parameters and variables of fn
+// and a global to its result
+func (g *generator) declare(fn *semantic.Function) {
+	f := Function{Name: fn.Name, Result: NoAddr}
+	for _, symbol := range fn.Scope.Symbols() {
+		g.addrs[symbol] = g.alloc(&f.LocalSize, Local, symbol.Type, symbol.Size(), symbol.Token)
+	}
+	for _, param := range fn.Params {
+		f.Params = append(f.Params, g.addrs[param])
+	}
+	if fn.Result != semantic.Void {
+		f.Result = g.alloc(&g.program.GlobalSize, Global, fn.Result, 1, fn.Token)
+	}
+
+	g.functions[fn.Name] = len(g.program.Functions)
+	g.program.Functions = append(g.program.Functions, f)
+}
+
+// initialize assigns the initial values of decls in declaration order,
+// globals get theirs before the main block and locals on every call
+func (g *generator) initialize(decls []*ast.VarDecl) {
+	for _, decl := range decls {
+		if decl.Value == nil {
+			continue
+		}
+		g.pos = decl.Token
+		size := *g.temps
+		g.expression(decl.Value)
+		g.emit(ASSIGN, g.popOperand(), NoAddr, g.variable(decl.Names[0]), decl.Token)
+		*g.temps = size
+	}
+}
+
+// stack makes size the segment block variables are allocated on
+func (g *generator) stack(size *Size, segment Segment) {
+	g.vars, g.segment, g.peak = size, segment, *size
+}
+
+// local allocates the variables of a block declaration and gives them
+// their initial value
+func (g *generator) local(decl *ast.VarDecl) {
+	for _, name := range decl.Names {
+		symbol := g.info.Idents[name]
+		g.addrs[symbol] = g.alloc(g.vars, g.segment, symbol.Type, symbol.Size(), symbol.Token)
+		g.peak = g.peak.max(*g.vars)
+		if decl.Value == nil {
+			g.emit(CLEAR, g.addrs[symbol], NoAddr, Addr(symbol.Size()), decl.Token)
+		}
+	}
+	g.initialize([]*ast.VarDecl{decl})
+}
+
+// Quadruples
+
+func (g *generator) emit(op Op, left, right, result Addr, tok token.Token) int {
+	g.program.Quads = append(g.program.Quads, Quad{
+		Op:     op,
+		Left:   left,
+		Right:  right,
+		Result: result,
+		Line:   tok.Line,
+	})
+	return len(g.program.Quads) - 1
+}
+
+// fill back-patches the jump at quad to continue at target
+func (g *generator) fill(quad int, target int) {
+	g.program.Quads[quad].Result = Addr(target)
+}
+
+func (g *generator) next() int {
+	return len(g.program.Quads)
+}
+
+// alloc reserves n slots of type t, programs that do not fit in the
+// address space of the segment are reported once
+func (g *generator) alloc(size *Size, segment Segment, t semantic.Type, n int, tok token.Token) Addr {
+	index := size.reserve(t, n)
+	if index <= TypeSpan && index+n > TypeSpan {
+		g.errorf(diag.OutOfMemory, tok, "too many %s values in %s memory", t, segment)
+	}
+	return NewAddr(segment, t, index)
+}
+
+func (g *generator) temp(t semantic.Type) Addr {
+	a := g.alloc(g.temps, Temp, t, 1, g.pos)
+	g.tempPeak = g.tempPeak.max(*g.temps)
+	return a
+}
+
+func (g *generator) variable(i *ast.Ident) Addr {
+	return g.addrs[g.info.Idents[i]]
+}
+
+// Stacks
+
+func (g *generator) pushOperand(a Addr) {
+	g.operands = append(g.operands, a)
+}
+
+func (g *generator) popOperand() Addr {
+	a := g.operands[len(g.operands)-1]
+	g.operands = g.operands[:len(g.operands)-1]
+	return a
+}
+
+func (g *generator) pushJump(quad int) {
+	g.jumps = append(g.jumps, quad)
+}
+
+func (g *generator) popJump() int {
+	quad := g.jumps[len(g.jumps)-1]
+	g.jumps = g.jumps[:len(g.jumps)-1]
+	return quad
+}
+
+// Statements
+
+// block generates b, the slots of its variables are free once it ends
+func (g *generator) block(b *ast.Block) {
+	if b == nil {
+		return
+	}
+	size := *g.vars
+	for _, stmt := range b.Statements {
+		g.statement(stmt)
+	}
+	*g.vars = size
+}
+
+// statement generates stmt, the temps it uses are free once it ends
+func (g *generator) statement(stmt ast.Statement) {
+	g.pos = stmt.Pos()
+	size := *g.temps
+	defer func() { *g.temps = size }()
+
+	switch s := stmt.(type) {
+	case *ast.Assign:
+		g.assign(s)
+	case *ast.If:
+		g.condition(s)
+	case *ast.While:
+		g.loop(s)
+	case *ast.For:
+		g.forLoop(s)
+	case *ast.Print:
+		for _, arg := range s.Args {
+			g.expression(arg)
+			g.emit(PRINT, g.popOperand(), NoAddr, NoAddr, s.Token)
+		}
+		g.emit(PRINTLN, NoAddr, NoAddr, NoAddr, s.Token)
+	case *ast.Return:
+		if s.Value == nil {
+			g.emit(ENDFUNC, NoAddr, NoAddr, NoAddr, s.Token)
+			return
+		}
+		g.expression(s.Value)
+		g.emit(RETURN, g.popOperand(), NoAddr, g.function.Result, s.Token)
+	case *ast.CallStmt:
+		g.call(s.Call)
+	case *ast.DeclStmt:
+		g.local(s.Decl)
+	}
+}
+
+func (g *generator) assign(s *ast.Assign) {
+	g.expression(s.Value)
+	value := g.popOperand()
+
+	switch target := s.Target.(type) {
+	case *ast.Ident:
+		g.emit(ASSIGN, value, NoAddr, g.variable(target), s.Token)
+	case *ast.Index:
+		base, offset := g.element(target)
+		if offset == NoAddr {
+			g.emit(ASSIGN, value, NoAddr, base, s.Token)
+		} else {
+			g.emit(STORE, value, offset, base, s.Token)
+		}
+	}
+}
+
+func (g *generator) condition(s *ast.If) {
+	g.expression(s.Condition)
+	g.pushJump(g.emit(GOTOF, g.popOperand(), NoAddr, NoAddr, s.Token))
+
+	g.block(s.Consequence)
+
+	if s.Alternative != nil {
+		end := g.emit(GOTO, NoAddr, NoAddr, NoAddr, s.Alternative.Token)
+		g.fill(g.popJump(), g.next())
+		g.pushJump(end)
+		g.block(s.Alternative)
+	}
+
+	g.fill(g.popJump(), g.next())
+}
+
+func (g *generator) loop(s *ast.While) {
+	start := g.next()
+	g.expression(s.Condition)
+	g.pushJump(g.emit(GOTOF, g.popOperand(), NoAddr, NoAddr, s.Token))
+
+	g.block(s.Body)
+
+	g.emit(GOTO, NoAddr, NoAddr, Addr(start), s.Token)
+	g.fill(g.popJump(), g.next())
+}
+
+// forLoop evaluates the range once, the loop runs while the variable is
+// not past the end in the direction of the step. When the sign of the step
+// is only known at runtime both tests are emitted.
+func (g *generator) forLoop(s *ast.For) {
+	variable := g.variable(s.Var)
+
+	g.expression(s.Start)
+	g.emit(ASSIGN, g.popOperand(), NoAddr, variable, s.Token)
+
+	end := g.temp(semantic.Int)
+	g.expression(s.End)
+	g.emit(ASSIGN, g.popOperand(), NoAddr, end, s.Token)
+
+	var step Addr
+	direction := int64(1)
+	switch {
+	case s.Step == nil:
+		step = g.intConstant(1)
+	default:
+		var constant bool
+		direction, constant = semantic.IntConstant(s.Step)
+		g.expression(s.Step)
+		step = g.popOperand()
+		if !constant {
+			direction = 0
+			copied := g.temp(semantic.Int)
+			g.emit(ASSIGN, step, NoAddr, copied, s.Token)
+			g.emit(CHECKSTEP, copied, NoAddr, NoAddr, s.Token)
+			step = copied
+		}
+	}
+
+	var negative Addr
+	if direction == 0 {
+		negative = g.temp(semantic.Bool)
+		g.emit(LESS_THAN, step, g.intConstant(0), negative, s.Token)
+	}
+
+	start := g.next()
+	inRange := g.temp(semantic.Bool)
+	switch {
+	case direction > 0:
+		g.emit(LESS_EQUAL, variable, end, inRange, s.Token)
+	case direction < 0:
+		g.emit(GREATER_EQUAL, variable, end, inRange, s.Token)
+	default:
+		positive := g.emit(GOTOF, negative, NoAddr, NoAddr, s.Token)
+		g.emit(GREATER_EQUAL, variable, end, inRange, s.Token)
+		test := g.emit(GOTO, NoAddr, NoAddr, NoAddr, s.Token)
+		g.fill(positive, g.next())
+		g.emit(LESS_EQUAL, variable, end, inRange, s.Token)
+		g.fill(test, g.next())
+	}
+	g.pushJump(g.emit(GOTOF, inRange, NoAddr, NoAddr, s.Token))
+
+	g.block(s.Body)
+
+	// the loop ends after the step that leaves end, so an end at the edge
+	// of the int range stops it even though the variable wraps around
+	g.emit(EQUAL, variable, end, inRange, s.Token)
+	g.emit(ADD, variable, step, variable, s.Token)
+	g.emit(GOTOF, inRange, NoAddr, Addr(start), s.Token)
+	g.fill(g.popJump(), g.next())
+}
+
+// Expressions
+
+func (g *generator) expression(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		g.pushOperand(g.variable(e))
+	case *ast.Literal:
+		g.pushOperand(g.constant(e))
+	case *ast.UnaryExpr:
+		if l, ok := semantic.SignedLiteral(e); ok {
+			g.pushOperand(g.constant(l))
+			return
+		}
+		g.expression(e.Operand)
+		switch e.Operator {
+		case token.MINUS:
+			result := g.temp(g.info.Types[e])
+			g.emit(NEG, g.popOperand(), NoAddr, result, e.Token)
+			g.pushOperand(result)
+		case token.NOT:
+			result := g.temp(semantic.Bool)
+			g.emit(NOT, g.popOperand(), NoAddr, result, e.Token)
+			g.pushOperand(result)
+		case token.BIT_NOT:
+			result := g.temp(semantic.Int)
+			g.emit(BNOT, g.popOperand(), NoAddr, result, e.Token)
+			g.pushOperand(result)
+		}
+	case *ast.BinaryExpr:
+		if e.Operator == token.AND || e.Operator == token.OR {
+			g.logical(e)
+			return
+		}
+		g.expression(e.Left)
+		g.expression(e.Right)
+		right := g.popOperand()
+		left := g.popOperand()
+		t := g.info.Types[e]
+		op := binaryOps[e.Operator]
+		if t == semantic.String {
+			op = CONCAT
+		}
+		result := g.temp(t)
+		g.emit(op, left, right, result, e.Token)
+		g.pushOperand(result)
+	case *ast.Index:
+		base, offset := g.element(e)
+		if offset == NoAddr {
+			g.pushOperand(base)
+			return
+		}
+		result := g.temp(g.info.Types[e])
+		g.emit(LOAD, base, offset, result, e.Token)
+		g.pushOperand(result)
+	case *ast.Call:
+		g.pushOperand(g.call(e))
+	}
+}
+
+// logical short-circuits && and ||, the right operand is only evaluated
+// when the left one does not decide the result
+func (g *generator) logical(e *ast.BinaryExpr) {
+	result := g.temp(semantic.Bool)
+
+	g.expression(e.Left)
+	g.emit(ASSIGN, g.popOperand(), NoAddr, result, e.Token)
+	jump := GOTOF
+	if e.Operator == token.OR {
+		jump = GOTOT
+	}
+	skip := g.emit(jump, result, NoAddr, NoAddr, e.Token)
+
+	g.expression(e.Right)
+	g.emit(ASSIGN, g.popOperand(), NoAddr, result, e.Token)
+	g.fill(skip, g.next())
+
+	g.pushOperand(result)
+}
+
+// element returns the address of the first slot an index can refer to and
+// the int holding the offset from it. Constant indices, which the checker
+// already bounded, are folded into the address, offset is NoAddr when every
+// index is constant. The others are verified against their dimension.
+func (g *generator) element(e *ast.Index) (Addr, Addr) {
+	symbol := g.info.Idents[e.Array]
+	base, offset := g.addrs[symbol], NoAddr
+
+	for i, index := range e.Indices {
+		stride := 1
+		for _, d := range symbol.Dims[i+1:] {
+			stride *= d
+		}
+
+		if v, ok := semantic.IntConstant(index); ok {
+			base += Addr(int(v) * stride)
+			continue
+		}
+
+		g.expression(index)
+		value := g.popOperand()
+		g.emit(VERIFY, value, NoAddr, Addr(symbol.Dims[i]), index.Pos())
+		if stride != 1 {
+			scaled := g.temp(semantic.Int)
+			g.emit(MUL, value, g.intConstant(int64(stride)), scaled, index.Pos())
+			value = scaled
+		}
+		if offset != NoAddr {
+			sum := g.temp(semantic.Int)
+			g.emit(ADD, offset, value, sum, index.Pos())
+			value = sum
+		}
+		offset = value
+	}
+
+	return base, offset
+}
+
+// call evaluates the arguments before reserving the frame so calls in them
+// do not interleave with it, the result is copied into a temp before it can
+// be overwritten by another call
+func (g *generator) call(c *ast.Call) Addr {
+	for _, arg := range c.Args {
+		g.expression(arg)
+	}
+	args := make([]Addr, len(c.Args))
+	for i := len(args) - 1; i >= 0; i-- {
+		args[i] = g.popOperand()
+	}
+
+	index, ok := g.functions[c.Function.Name]
+	if !ok && c.Function.Name == semantic.Len {
+		result := g.temp(semantic.Int)
+		g.emit(LEN, args[0], NoAddr, result, c.Token)
+		return result
+	}
+	g.emit(ERA, NoAddr, NoAddr, Addr(index), c.Token)
+	for i, arg := range args {
+		g.emit(PARAM, arg, NoAddr, Addr(i), c.Token)
+	}
+	g.emit(GOSUB, NoAddr, NoAddr, Addr(index), c.Token)
+
+	f := g.program.Functions[index]
+	if f.Result == NoAddr {
+		return NoAddr
+	}
+	result := g.temp(f.Result.Type())
+	g.emit(ASSIGN, f.Result, NoAddr, result, c.Token)
+	return result
+}
+
+func (g *generator) constant(l *ast.Literal) Addr {
+	t := semantic.TypeOf(l.Token.Type)
+	key := constantKey{t: t, literal: l.Token.Literal}
+	if t == semantic.String {
+		// "a" and `a` are the same constant
+		key.literal = l.Token.Value
+	}
+	if addr, ok := g.constants[key]; ok {
+		return addr
+	}
+
+	consts := &g.program.Constants
+	var index int
+
+	switch t {
+	case semantic.Int:
+		v, err := semantic.IntValue(l.Token.Literal)
+		if err != nil {
+			g.errorf(diag.InvalidConst, l.Token, "invalid int literal %s", l.Token.Literal)
+		}
+		index = len(consts.Ints)
+		consts.Ints = append(consts.Ints, v)
+	case semantic.Float:
+		v, err := semantic.FloatValue(l.Token.Literal)
+		if err != nil {
+			g.errorf(diag.InvalidConst, l.Token, "invalid float literal %s", l.Token.Literal)
+		}
+		index = len(consts.Floats)
+		consts.Floats = append(consts.Floats, v)
+	case semantic.Bool:
+		index = len(consts.Bools)
+		consts.Bools = append(consts.Bools, l.Token.Literal == "true")
+	case semantic.String:
+		index = len(consts.Strings)
+		consts.Strings = append(consts.Strings, l.Token.Value)
+	}
+
+	addr := NewAddr(Const, t, index)
+	g.constants[key] = addr
+	return addr
+}
+
+func (g *generator) intConstant(v int64) Addr {
+	return g.constant(&ast.Literal{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(v, 10)}})
+}
+
+func (g *generator) errorf(code diag.Code, tok token.Token, format string, args ...interface{}) {
+	g.diagnostics.Add(diag.Errorf(code, tok, format, args...))
+}
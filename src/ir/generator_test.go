@@ -0,0 +1,533 @@
+package ir
+
+import (
+	"ciri/src/goyacc"
+	"ciri/src/semantic"
+	"math"
+	"strings"
+	"testing"
+)
+
+func generate(t *testing.T, input string) *Program {
+	program, err := goyacc.Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := semantic.Check(program)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	p, err := Generate(program, info)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return p
+}
+
+type expectedQuad struct {
+	op     Op
+	left   Addr
+	right  Addr
+	result Addr
+}
+
+func expectQuads(t *testing.T, p *Program, expected []expectedQuad) {
+	if len(p.Quads) != len(expected) {
+		t.Fatalf("expected %d quadruples, got=%d\n%s", len(expected), len(p.Quads), p)
+	}
+
+	for i, e := range expected {
+		q := p.Quads[i]
+		if q.Op != e.op || q.Left != e.left || q.Right != e.right || q.Result != e.result {
+			t.Fatalf("quads[%d] wrong. expected=%v, got=%v\n%s", i, e, q, p)
+		}
+	}
+}
+
+var (
+	gInt0   = NewAddr(Global, semantic.Int, 0)
+	gInt1   = NewAddr(Global, semantic.Int, 1)
+	gFloat0 = NewAddr(Global, semantic.Float, 0)
+	tInt0   = NewAddr(Temp, semantic.Int, 0)
+	tInt1   = NewAddr(Temp, semantic.Int, 1)
+	tFloat0 = NewAddr(Temp, semantic.Float, 0)
+	tBool0  = NewAddr(Temp, semantic.Bool, 0)
+	cInt0   = NewAddr(Const, semantic.Int, 0)
+	cInt1   = NewAddr(Const, semantic.Int, 1)
+	cInt2   = NewAddr(Const, semantic.Int, 2)
+	cFloat0 = NewAddr(Const, semantic.Float, 0)
+	cStr0   = NewAddr(Const, semantic.String, 0)
+)
+
+func TestGenerateExpression(t *testing.T) {
+	input := `
+		program test: var x, y: int; f: float; {
+			x = 10;
+			y = x + 2 * 10;
+			f = -x / 1.5;
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, cInt0, NoAddr, gInt0},
+		{MUL, cInt1, cInt0, tInt0},
+		{ADD, gInt0, tInt0, tInt1},
+		{ASSIGN, tInt1, NoAddr, gInt1},
+		{NEG, gInt0, NoAddr, tInt0},
+		{DIV, tInt0, cFloat0, tFloat0},
+		{ASSIGN, tFloat0, NoAddr, gFloat0},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+
+	if len(p.Constants.Ints) != 2 || p.Constants.Ints[0] != 10 || p.Constants.Ints[1] != 2 {
+		t.Fatalf("int constants wrong, got=%v", p.Constants.Ints)
+	}
+	// temps are reused by the next statement, the frame fits the largest
+	if p.TempSize.Ints != 2 || p.TempSize.Floats != 1 {
+		t.Fatalf("temp size wrong, got=%+v", p.TempSize)
+	}
+}
+
+func TestGenerateIfElse(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			if (x < 10) {
+				print("small");
+			} else {
+				x = 1;
+			};
+			x = 2;
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{LESS_THAN, gInt0, cInt0, tBool0},
+		{GOTOF, tBool0, NoAddr, 5},
+		{PRINT, cStr0, NoAddr, NoAddr},
+		{PRINTLN, NoAddr, NoAddr, NoAddr},
+		{GOTO, NoAddr, NoAddr, 6},
+		{ASSIGN, cInt1, NoAddr, gInt0},
+		{ASSIGN, cInt2, NoAddr, gInt0},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+
+	if p.Constants.Strings[0] != "small" {
+		t.Fatalf("string constant wrong. expected=%q, got=%q", "small", p.Constants.Strings[0])
+	}
+}
+
+func TestGenerateNestedIf(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			if (x > 1) {
+				if (x > 2) {
+					x = 3;
+				};
+			};
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{GREATER_THAN, gInt0, cInt0, tBool0},
+		{GOTOF, tBool0, NoAddr, 5},
+		{GREATER_THAN, gInt0, cInt1, NewAddr(Temp, semantic.Bool, 1)},
+		{GOTOF, NewAddr(Temp, semantic.Bool, 1), NoAddr, 5},
+		{ASSIGN, cInt2, NoAddr, gInt0},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestProgramString(t *testing.T) {
+	input := `
+		program test: var x, d : int;  {
+			x = 10;
+			x = 20 + 50 + d;
+
+			if (x < 10) {
+				print("hello");
+			};
+
+			print("hello", x, x > 10);
+		}
+	`
+	p := generate(t, input)
+	t.Log("\n" + p.String())
+
+	if p.Globals[1].Name != "d" || p.Globals[1].Addr != gInt1 {
+		t.Fatalf("globals wrong, got=%v", p.Globals)
+	}
+}
+
+func TestGenerateFor(t *testing.T) {
+	input := `
+		program test: var i: int; {
+			for i = 0 to 10 step 2 {
+				print(i);
+			};
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, cInt0, NoAddr, gInt0},
+		{ASSIGN, cInt1, NoAddr, tInt0},
+		{LESS_EQUAL, gInt0, tInt0, tBool0},
+		{GOTOF, tBool0, NoAddr, 9},
+		{PRINT, gInt0, NoAddr, NoAddr},
+		{PRINTLN, NoAddr, NoAddr, NoAddr},
+		{EQUAL, gInt0, tInt0, tBool0},
+		{ADD, gInt0, cInt2, gInt0},
+		{GOTOF, tBool0, NoAddr, 2},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestGenerateForDynamicStep(t *testing.T) {
+	input := `
+		program test: var i, s: int; {
+			for i = 0 to 10 step s {};
+		}
+	`
+	p := generate(t, input)
+
+	tInt1 := NewAddr(Temp, semantic.Int, 1)
+	tBool1 := NewAddr(Temp, semantic.Bool, 1)
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, cInt0, NoAddr, gInt0},
+		{ASSIGN, cInt1, NoAddr, tInt0},
+		{ASSIGN, gInt1, NoAddr, tInt1},
+		{CHECKSTEP, tInt1, NoAddr, NoAddr},
+		{LESS_THAN, tInt1, cInt0, tBool0},
+		{GOTOF, tBool0, NoAddr, 8},
+		{GREATER_EQUAL, gInt0, tInt0, tBool1},
+		{GOTO, NoAddr, NoAddr, 9},
+		{LESS_EQUAL, gInt0, tInt0, tBool1},
+		{GOTOF, tBool1, NoAddr, 13},
+		{EQUAL, gInt0, tInt0, tBool1},
+		{ADD, gInt0, tInt1, gInt0},
+		{GOTOF, tBool1, NoAddr, 5},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestGenerateWhile(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			x = 0;
+			while (x < 10) {
+				x = x + 1;
+			};
+			print(x);
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, cInt0, NoAddr, gInt0},
+		{LESS_THAN, gInt0, cInt1, tBool0},
+		{GOTOF, tBool0, NoAddr, 6},
+		{ADD, gInt0, cInt2, tInt0},
+		{ASSIGN, tInt0, NoAddr, gInt0},
+		{GOTO, NoAddr, NoAddr, 1},
+		{PRINT, gInt0, NoAddr, NoAddr},
+		{PRINTLN, NoAddr, NoAddr, NoAddr},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestGenerateFunction(t *testing.T) {
+	input := `
+		program test: var x: int;
+		func twice(n: int): int {
+			return n * 2;
+		}
+		{
+			x = twice(x + 1);
+		}
+	`
+	p := generate(t, input)
+
+	lInt0 := NewAddr(Local, semantic.Int, 0)
+	expectQuads(t, p, []expectedQuad{
+		{ADD, gInt0, cInt0, tInt0},
+		{ERA, NoAddr, NoAddr, 0},
+		{PARAM, tInt0, NoAddr, 0},
+		{GOSUB, NoAddr, NoAddr, 0},
+		{ASSIGN, gInt1, NoAddr, tInt1},
+		{ASSIGN, tInt1, NoAddr, gInt0},
+		{END, NoAddr, NoAddr, NoAddr},
+		{MUL, lInt0, cInt1, tInt0},
+		{RETURN, tInt0, NoAddr, gInt1},
+		{ENDFUNC, NoAddr, NoAddr, NoAddr},
+	})
+
+	f := p.Functions[0]
+	if f.Name != "twice" || f.Start != 7 || len(f.Params) != 1 || f.Params[0] != lInt0 || f.Result != gInt1 {
+		t.Fatalf("function wrong, got=%+v", f)
+	}
+	if f.LocalSize.Ints != 1 || f.TempSize.Ints != 1 || p.TempSize.Ints != 2 {
+		t.Fatalf("frame sizes wrong, got locals=%+v temps=%+v main temps=%+v", f.LocalSize, f.TempSize, p.TempSize)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestGenerateArrays(t *testing.T) {
+	input := `
+		program test: var a[4]: int; grid[2][3]: int; i: int; {
+			a[2] = i;
+			grid[i][1] = a[i];
+		}
+	`
+	p := generate(t, input)
+
+	g := func(i int) Addr { return NewAddr(Global, semantic.Int, i) }
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, g(10), NoAddr, g(2)},
+		{VERIFY, g(10), NoAddr, 4},
+		{LOAD, g(0), g(10), tInt0},
+		{VERIFY, g(10), NoAddr, 2},
+		{MUL, g(10), cInt0, tInt1},
+		{STORE, tInt0, tInt1, g(5)},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+	if p.GlobalSize.Ints != 11 || p.Constants.Ints[0] != 3 {
+		t.Fatalf("memory wrong, got globals=%+v constants=%v", p.GlobalSize, p.Constants.Ints)
+	}
+}
+
+func TestGenerateOutOfMemory(t *testing.T) {
+	program, err := goyacc.Parse(`program test: var a[6000], b[6000]: int; {}`)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := semantic.Check(program)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	_, err = Generate(program, info)
+	if err == nil || !strings.Contains(err.Error(), "too many int values in global memory") {
+		t.Fatalf("expected an out of memory error, got=%v", err)
+	}
+}
+
+func TestGenerateReusesTemps(t *testing.T) {
+	input := "program test: var x, n: int; {" + strings.Repeat("x = x + 1;\n", 1000) +
+		"for x = 0 to n step n * 2 { x = x * (x + 1); }; }"
+	p := generate(t, input)
+
+	// the for loop keeps its limit and step while its body runs
+	expected := Size{Ints: 5, Bools: 2}
+	if p.TempSize != expected {
+		t.Fatalf("temp size wrong. expected=%+v, got=%+v", expected, p.TempSize)
+	}
+}
+
+func TestGenerateLogical(t *testing.T) {
+	input := `
+		program test: var a, b, c: bool; {
+			a = b || !c;
+			a = true && a;
+		}
+	`
+	p := generate(t, input)
+
+	gBool := func(i int) Addr { return NewAddr(Global, semantic.Bool, i) }
+	tBool1 := NewAddr(Temp, semantic.Bool, 1)
+	cTrue := NewAddr(Const, semantic.Bool, 0)
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, gBool(1), NoAddr, tBool0},
+		{GOTOT, tBool0, NoAddr, 4},
+		{NOT, gBool(2), NoAddr, tBool1},
+		{ASSIGN, tBool1, NoAddr, tBool0},
+		{ASSIGN, tBool0, NoAddr, gBool(0)},
+		{ASSIGN, cTrue, NoAddr, tBool0},
+		{GOTOF, tBool0, NoAddr, 8},
+		{ASSIGN, gBool(0), NoAddr, tBool0},
+		{ASSIGN, tBool0, NoAddr, gBool(0)},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+	if len(p.Constants.Bools) != 1 || !p.Constants.Bools[0] {
+		t.Fatalf("constants wrong, got=%v", p.Constants.Bools)
+	}
+}
+
+func TestGenerateBitwise(t *testing.T) {
+	input := `
+		program test: var x, y: int; {
+			x = ~y & 255 | x % 4;
+			y = x << 2 >> y ^ 1;
+		}
+	`
+	p := generate(t, input)
+
+	gInt1 := NewAddr(Global, semantic.Int, 1)
+	tInt := func(i int) Addr { return NewAddr(Temp, semantic.Int, i) }
+	cInt := func(i int) Addr { return NewAddr(Const, semantic.Int, i) }
+	expectQuads(t, p, []expectedQuad{
+		{BNOT, gInt1, NoAddr, tInt(0)},
+		{BAND, tInt(0), cInt(0), tInt(1)},
+		{MOD, gInt0, cInt(1), tInt(2)},
+		{BOR, tInt(1), tInt(2), tInt(3)},
+		{ASSIGN, tInt(3), NoAddr, gInt0},
+		{SHL, gInt0, cInt(2), tInt(0)},
+		{SHR, tInt(0), gInt1, tInt(1)},
+		{BXOR, tInt(1), cInt(3), tInt(2)},
+		{ASSIGN, tInt(2), NoAddr, gInt1},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestGenerateUnaryMinus(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; {
+			x = -5 - -x;
+			f = -(f + 1.5);
+			x = -9223372036854775808;
+		}
+	`
+	p := generate(t, input)
+
+	gFloat0 := NewAddr(Global, semantic.Float, 0)
+	tInt := func(i int) Addr { return NewAddr(Temp, semantic.Int, i) }
+	tFloat := func(i int) Addr { return NewAddr(Temp, semantic.Float, i) }
+	expectQuads(t, p, []expectedQuad{
+		{NEG, gInt0, NoAddr, tInt(0)},
+		{SUB, cInt0, tInt(0), tInt(1)},
+		{ASSIGN, tInt(1), NoAddr, gInt0},
+		{ADD, gFloat0, NewAddr(Const, semantic.Float, 0), tFloat(0)},
+		{NEG, tFloat(0), NoAddr, tFloat(1)},
+		{ASSIGN, tFloat(1), NoAddr, gFloat0},
+		{ASSIGN, NewAddr(Const, semantic.Int, 1), NoAddr, gInt0},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+	if ints := p.Constants.Ints; len(ints) != 2 || ints[0] != -5 || ints[1] != math.MinInt64 {
+		t.Fatalf("constants wrong, got=%v", ints)
+	}
+}
+
+func TestGenerateStrings(t *testing.T) {
+	input := `
+		program test: var msg: string; n: int; ok: bool; {
+			msg = "id=" + n;
+			n = len(msg);
+			ok = msg != "";
+		}
+	`
+	p := generate(t, input)
+
+	gString0 := NewAddr(Global, semantic.String, 0)
+	tString0 := NewAddr(Temp, semantic.String, 0)
+	cString := func(i int) Addr { return NewAddr(Const, semantic.String, i) }
+	expectQuads(t, p, []expectedQuad{
+		{CONCAT, cString(0), gInt0, tString0},
+		{ASSIGN, tString0, NoAddr, gString0},
+		{LEN, gString0, NoAddr, tInt0},
+		{ASSIGN, tInt0, NoAddr, gInt0},
+		{NOT_EQUAL, gString0, cString(1), tBool0},
+		{ASSIGN, tBool0, NoAddr, NewAddr(Global, semantic.Bool, 0)},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+	if s := p.Constants.Strings; len(s) != 2 || s[0] != "id=" || s[1] != "" {
+		t.Fatalf("constants wrong, got=%q", s)
+	}
+}
+
+func TestGenerateInitializedVars(t *testing.T) {
+	input := `
+		program test: var x = 10; f: float = x + 1;
+		func g(): int {
+			var n = 3;
+			return n;
+		}
+		{
+			print(f);
+		}
+	`
+	p := generate(t, input)
+
+	gFloat0 := NewAddr(Global, semantic.Float, 0)
+	lInt0 := NewAddr(Local, semantic.Int, 0)
+	expectQuads(t, p, []expectedQuad{
+		{ASSIGN, cInt0, NoAddr, gInt0},
+		{ADD, gInt0, NewAddr(Const, semantic.Int, 1), tInt0},
+		{ASSIGN, tInt0, NoAddr, gFloat0},
+		{PRINT, gFloat0, NoAddr, NoAddr},
+		{PRINTLN, NoAddr, NoAddr, NoAddr},
+		{END, NoAddr, NoAddr, NoAddr},
+		{ASSIGN, NewAddr(Const, semantic.Int, 2), NoAddr, lInt0},
+		{RETURN, lInt0, NoAddr, NewAddr(Global, semantic.Int, 1)},
+		{ENDFUNC, NoAddr, NoAddr, NoAddr},
+	})
+}
+
+func TestGenerateBlockVars(t *testing.T) {
+	input := `
+		program test: var x: int;
+		{
+			if (x > 0) {
+				var a: int;
+				a = 1;
+			} else {
+				var b = 2;
+				print(b);
+			};
+		}
+	`
+	p := generate(t, input)
+
+	expectQuads(t, p, []expectedQuad{
+		{GREATER_THAN, gInt0, cInt0, tBool0},
+		{GOTOF, tBool0, NoAddr, 5},
+		{CLEAR, gInt1, NoAddr, 1},
+		{ASSIGN, cInt1, NoAddr, gInt1},
+		{GOTO, NoAddr, NoAddr, 8},
+		{ASSIGN, cInt2, NoAddr, gInt1},
+		{PRINT, gInt1, NoAddr, NoAddr},
+		{PRINTLN, NoAddr, NoAddr, NoAddr},
+		{END, NoAddr, NoAddr, NoAddr},
+	})
+
+	// sibling blocks share their slots
+	if p.GlobalSize.Ints != 2 || len(p.Globals) != 1 {
+		t.Fatalf("globals wrong. expected 2 int slots and 1 global, got=%+v %v", p.GlobalSize, p.Globals)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestGenerateFunctionBlockVars(t *testing.T) {
+	input := `
+		program test:
+		func f(n: int) {
+			var s: string;
+			while (n > 0) {
+				var arr[3]: float;
+				n = n - 1;
+			};
+			if (n == 0) {
+				var a, b: float;
+				print(a, b, s);
+			};
+		}
+		{ f(1); }
+	`
+	p := generate(t, input)
+
+	f := p.Functions[0]
+	expected := Size{Ints: 1, Floats: 3, Strings: 1}
+	if f.LocalSize != expected {
+		t.Fatalf("LocalSize wrong. expected=%+v, got=%+v", expected, f.LocalSize)
+	}
+	if p.GlobalSize != (Size{}) {
+		t.Fatalf("function block variables should be local, got=%+v", p.GlobalSize)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
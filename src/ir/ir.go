@@ -0,0 +1,363 @@
+package ir
+
+import (
+	"bytes"
+	"ciri/src/semantic"
+	"fmt"
+	"strings"
+)
+
+type Op uint8
+
+const (
+	ADD Op = iota
+	SUB
+	MUL
+	DIV
+	NEG
+	LESS_THAN
+	GREATER_THAN
+	NOT_EQUAL
+	ASSIGN
+	PRINT
+	PRINTLN
+	GOTO
+	GOTOF
+	END
+	LESS_EQUAL
+	GREATER_EQUAL
+	// ERA reserves the frame of the function in Result, PARAM copies Left
+	// into its parameter number Result and GOSUB calls it
+	ERA
+	PARAM
+	GOSUB
+	// RETURN copies Left into the result global in Result and leaves the
+	// function, ENDFUNC leaves it without a value
+	RETURN
+	ENDFUNC
+	// VERIFY checks that the index in Left is inside [0, Result)
+	VERIFY
+	// LOAD copies the element Right slots after the array at Left into
+	// Result, STORE copies Left into the element Right slots after Result
+	LOAD
+	STORE
+	NOT
+	// GOTOT jumps when Left is true, it short-circuits ||
+	GOTOT
+	EQUAL
+	// integer only operations, BNOT is the unary complement of Left
+	MOD
+	BAND
+	BOR
+	BXOR
+	BNOT
+	SHL
+	SHR
+	// CONCAT joins Left and Right into a string, numbers are formatted the
+	// way PRINT writes them. LEN stores the byte length of Left
+	CONCAT
+	LEN
+	// CLEAR zeroes the Result slots starting at Left, block variables get a
+	// fresh value every time their declaration runs
+	CLEAR
+	// CHECKSTEP fails when the for loop step in Left is zero, steps only
+	// known at runtime are checked once before the loop
+	CHECKSTEP
+)
+
+var opNames = [...]string{
+	ADD:           "+",
+	SUB:           "-",
+	MUL:           "*",
+	DIV:           "/",
+	NEG:           "NEG",
+	LESS_THAN:     "<",
+	GREATER_THAN:  ">",
+	NOT_EQUAL:     "<>",
+	ASSIGN:        "=",
+	PRINT:         "PRINT",
+	PRINTLN:       "PRINTLN",
+	GOTO:          "GOTO",
+	GOTOF:         "GOTOF",
+	END:           "END",
+	LESS_EQUAL:    "<=",
+	GREATER_EQUAL: ">=",
+	ERA:           "ERA",
+	PARAM:         "PARAM",
+	GOSUB:         "GOSUB",
+	RETURN:        "RETURN",
+	ENDFUNC:       "ENDFUNC",
+	VERIFY:        "VERIFY",
+	LOAD:          "LOAD",
+	STORE:         "STORE",
+	NOT:           "!",
+	GOTOT:         "GOTOT",
+	EQUAL:         "==",
+	MOD:           "%",
+	BAND:          "&",
+	BOR:           "|",
+	BXOR:          "^",
+	BNOT:          "~",
+	SHL:           "<<",
+	SHR:           ">>",
+	CONCAT:        "CONCAT",
+	LEN:           "LEN",
+	CLEAR:         "CLEAR",
+	CHECKSTEP:     "CHECKSTEP",
+}
+
+// Valid reports whether o is a known operation
+func (o Op) Valid() bool {
+	return int(o) < len(opNames)
+}
+
+// IsJump reports whether the Result of o is a quadruple index
+func (o Op) IsJump() bool {
+	return o == GOTO || o == GOTOF || o == GOTOT
+}
+
+func (o Op) String() string {
+	if int(o) < len(opNames) {
+		return opNames[o]
+	}
+	return fmt.Sprintf("Op(%d)", o)
+}
+
+// binaryOps maps source operators to the quadruple that implements them
+var binaryOps = map[string]Op{
+	"+":  ADD,
+	"-":  SUB,
+	"*":  MUL,
+	"/":  DIV,
+	"<":  LESS_THAN,
+	">":  GREATER_THAN,
+	"<>": NOT_EQUAL,
+	"!=": NOT_EQUAL,
+	"<=": LESS_EQUAL,
+	">=": GREATER_EQUAL,
+	"==": EQUAL,
+	"%":  MOD,
+	"&":  BAND,
+	"|":  BOR,
+	"^":  BXOR,
+	"<<": SHL,
+	">>": SHR,
+}
+
+// Memory map
+
+type Segment int
+
+const (
+	Global Segment = iota
+	Temp
+	Const
+	// Local is the segment of parameters and local variables, like Temp it
+	// belongs to the frame of the running function
+	Local
+)
+
+func (s Segment) String() string {
+	switch s {
+	case Global:
+		return "global"
+	case Temp:
+		return "temp"
+	case Const:
+		return "const"
+	case Local:
+		return "local"
+	default:
+		return fmt.Sprintf("Segment(%d)", int(s))
+	}
+}
+
+// Every segment is split in one range per type
+const (
+	TypeSpan    = 10000
+	SegmentSpan = 4 * TypeSpan
+)
+
+// Addr is a virtual address, it encodes the segment, the type and the index
+// of a value. Jump quadruples store the target quadruple in Result instead.
+type Addr int
+
+// NoAddr marks an unused operand
+const NoAddr Addr = -1
+
+func NewAddr(segment Segment, t semantic.Type, index int) Addr {
+	return Addr(int(segment)*SegmentSpan + int(t-semantic.Int)*TypeSpan + index)
+}
+
+func (a Addr) Segment() Segment {
+	return Segment(int(a) / SegmentSpan)
+}
+
+func (a Addr) Type() semantic.Type {
+	return semantic.Type(int(a)%SegmentSpan/TypeSpan) + semantic.Int
+}
+
+func (a Addr) Index() int {
+	return int(a) % TypeSpan
+}
+
+// Size is the number of slots a segment uses for each type
+type Size struct {
+	Ints    int
+	Floats  int
+	Bools   int
+	Strings int
+}
+
+// of returns the slots used by type t
+func (s Size) of(t semantic.Type) int {
+	switch t {
+	case semantic.Int:
+		return s.Ints
+	case semantic.Float:
+		return s.Floats
+	case semantic.Bool:
+		return s.Bools
+	default:
+		return s.Strings
+	}
+}
+
+// reserve hands out n consecutive indexes for type t and returns the first
+func (s *Size) reserve(t semantic.Type, n int) int {
+	var counter *int
+	switch t {
+	case semantic.Int:
+		counter = &s.Ints
+	case semantic.Float:
+		counter = &s.Floats
+	case semantic.Bool:
+		counter = &s.Bools
+	default:
+		counter = &s.Strings
+	}
+	index := *counter
+	*counter += n
+	return index
+}
+
+// max returns the larger count of s and o for every type
+func (s Size) max(o Size) Size {
+	if o.Ints > s.Ints {
+		s.Ints = o.Ints
+	}
+	if o.Floats > s.Floats {
+		s.Floats = o.Floats
+	}
+	if o.Bools > s.Bools {
+		s.Bools = o.Bools
+	}
+	if o.Strings > s.Strings {
+		s.Strings = o.Strings
+	}
+	return s
+}
+
+// Constants is the constant table, values are stored at the index of
+// their address
+type Constants struct {
+	Ints    []int64
+	Floats  []float64
+	Bools   []bool
+	Strings []string
+}
+
+type Quad struct {
+	Op     Op
+	Left   Addr
+	Right  Addr
+	Result Addr
+	Line   uint32
+}
+
+func (q Quad) String() string {
+	return fmt.Sprintf("%-8s %6s %6s %6s", q.Op, operand(q.Left), operand(q.Right), operand(q.Result))
+}
+
+func operand(a Addr) string {
+	if a == NoAddr {
+		return "_"
+	}
+	return fmt.Sprint(int(a))
+}
+
+type Symbol struct {
+	Name string
+	Addr Addr
+}
+
+// Function is the entry point and frame layout of a compiled function
+type Function struct {
+	Name  string
+	Start int
+	// Params are the local addresses arguments are copied to
+	Params []Addr
+	// Result is the global the return value is left in, NoAddr for
+	// functions that return nothing
+	Result    Addr
+	LocalSize Size
+	TempSize  Size
+}
+
+// Program is a lowered ciri program, the quadruples of the main block come
+// first and end with END, every function follows ending with ENDFUNC
+type Program struct {
+	Name      string
+	Quads     []Quad
+	Constants Constants
+	Globals   []Symbol
+	Functions []Function
+	// GlobalSize and TempSize are the slots the VM has to reserve, TempSize
+	// is the temp frame of the main block
+	GlobalSize Size
+	TempSize   Size
+}
+
+// String dumps the constant table, the globals and the quadruple list
+func (p *Program) String() string {
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "program %s\n", p.Name)
+
+	out.WriteString("constants:\n")
+	for i, v := range p.Constants.Ints {
+		fmt.Fprintf(&out, "  %6d  %d\n", NewAddr(Const, semantic.Int, i), v)
+	}
+	for i, v := range p.Constants.Floats {
+		fmt.Fprintf(&out, "  %6d  %g\n", NewAddr(Const, semantic.Float, i), v)
+	}
+	for i, v := range p.Constants.Bools {
+		fmt.Fprintf(&out, "  %6d  %t\n", NewAddr(Const, semantic.Bool, i), v)
+	}
+	for i, v := range p.Constants.Strings {
+		fmt.Fprintf(&out, "  %6d  %q\n", NewAddr(Const, semantic.String, i), v)
+	}
+
+	out.WriteString("globals:\n")
+	for _, s := range p.Globals {
+		fmt.Fprintf(&out, "  %6d  %s %s\n", s.Addr, s.Name, s.Addr.Type())
+	}
+
+	if len(p.Functions) > 0 {
+		out.WriteString("functions:\n")
+	}
+	for _, f := range p.Functions {
+		params := make([]string, len(f.Params))
+		for i, a := range f.Params {
+			params[i] = fmt.Sprint(int(a))
+		}
+		fmt.Fprintf(&out, "  %6d  %s(%s) %s\n", f.Start, f.Name, strings.Join(params, ", "), operand(f.Result))
+	}
+
+	out.WriteString("quadruples:\n")
+	for i, q := range p.Quads {
+		fmt.Fprintf(&out, "  %4d  %s\n", i, q)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,33 @@
+package ir
+
+import (
+	"ciri/src/semantic"
+	"testing"
+)
+
+func TestAddr(t *testing.T) {
+	tests := []struct {
+		segment Segment
+		t       semantic.Type
+		index   int
+	}{
+		{Global, semantic.Int, 0},
+		{Global, semantic.String, 9999},
+		{Temp, semantic.Float, 12},
+		{Const, semantic.Bool, 3},
+	}
+
+	for i, tt := range tests {
+		addr := NewAddr(tt.segment, tt.t, tt.index)
+
+		if addr.Segment() != tt.segment {
+			t.Fatalf("tests[%d] - segment wrong. expected=%s, got=%s", i, tt.segment, addr.Segment())
+		}
+		if addr.Type() != tt.t {
+			t.Fatalf("tests[%d] - type wrong. expected=%s, got=%s", i, tt.t, addr.Type())
+		}
+		if addr.Index() != tt.index {
+			t.Fatalf("tests[%d] - index wrong. expected=%d, got=%d", i, tt.index, addr.Index())
+		}
+	}
+}
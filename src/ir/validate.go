@@ -0,0 +1,298 @@
+package ir
+
+import (
+	"ciri/src/semantic"
+	"errors"
+	"fmt"
+)
+
+// Validate checks that every quadruple is a known operation whose operands
+// point inside the program memory and have the types the operation reads,
+// so a program loaded from outside the compiler cannot make the VM read out
+// of bounds
+func (p *Program) Validate() error {
+	main := len(p.Quads)
+	if len(p.Functions) > 0 {
+		main = p.Functions[0].Start
+	}
+	if main <= 0 || main > len(p.Quads) || p.Quads[main-1].Op != END {
+		return fmt.Errorf("program does not end with %s", END)
+	}
+	if err := p.validCode(0, main, nil); err != nil {
+		return err
+	}
+
+	for i, f := range p.Functions {
+		end := len(p.Quads)
+		if i+1 < len(p.Functions) {
+			end = p.Functions[i+1].Start
+			if end > len(p.Quads) {
+				return fmt.Errorf("function %s: invalid start %d", p.Functions[i+1].Name, end)
+			}
+		}
+		if f.Start < main || f.Start >= end {
+			return fmt.Errorf("function %s: invalid start %d", f.Name, f.Start)
+		}
+		if p.Quads[end-1].Op != ENDFUNC {
+			return fmt.Errorf("function %s does not end with %s", f.Name, ENDFUNC)
+		}
+		main = end
+
+		for _, a := range f.Params {
+			if a.Segment() != Local {
+				return fmt.Errorf("function %s: parameter address %d is not local", f.Name, a)
+			}
+			if err := p.validAddr(a, f.LocalSize, Size{}); err != nil {
+				return fmt.Errorf("function %s: %s", f.Name, err)
+			}
+		}
+		if f.Result != NoAddr && f.Result.Segment() != Global {
+			return fmt.Errorf("function %s: result address %d is not global", f.Name, f.Result)
+		}
+		if err := p.validAddr(f.Result, Size{}, Size{}); err != nil {
+			return fmt.Errorf("function %s: %s", f.Name, err)
+		}
+
+		if err := p.validCode(f.Start, end, &p.Functions[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range p.Globals {
+		if s.Addr.Segment() != Global {
+			return fmt.Errorf("global %s: address %d is not global", s.Name, s.Addr)
+		}
+		if err := p.validAddr(s.Addr, Size{}, Size{}); err != nil {
+			return fmt.Errorf("global %s: %s", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validCode checks the quadruples in [start, end) of function f, or of the
+// main block when f is nil. Jumps may not leave the range or land inside a
+// call, and calls must be an ERA followed by its PARAMs and the GOSUB.
+func (p *Program) validCode(start, end int, f *Function) error {
+	locals, temps := Size{}, p.TempSize
+	if f != nil {
+		locals, temps = f.LocalSize, f.TempSize
+	}
+	callee := -1
+	// inCall marks the PARAMs and GOSUBs, the VM needs the ERA before them
+	inCall := make(map[int]bool)
+	var jumps []int
+
+	for i := start; i < end; i++ {
+		q := p.Quads[i]
+		if !q.Op.Valid() {
+			return fmt.Errorf("quad %d: unknown operation %d", i, q.Op)
+		}
+		for _, a := range []Addr{q.Left, q.Right} {
+			if err := p.validAddr(a, locals, temps); err != nil {
+				return fmt.Errorf("quad %d: %s", i, err)
+			}
+		}
+		if callee >= 0 && q.Op != PARAM && q.Op != GOSUB {
+			return fmt.Errorf("quad %d: %s inside the call to %s", i, q.Op, p.Functions[callee].Name)
+		}
+		inCall[i] = callee >= 0
+		if err := validTypes(q); err != nil {
+			return fmt.Errorf("quad %d: %s %s", i, q.Op, err)
+		}
+
+		switch q.Op {
+		case GOTO, GOTOF, GOTOT:
+			if int(q.Result) < start || int(q.Result) >= end {
+				return fmt.Errorf("quad %d: jump target %d out of range", i, q.Result)
+			}
+			jumps = append(jumps, i)
+		case ERA:
+			if q.Result < 0 || int(q.Result) >= len(p.Functions) {
+				return fmt.Errorf("quad %d: unknown function %d", i, q.Result)
+			}
+			callee = int(q.Result)
+		case PARAM:
+			if callee < 0 {
+				return fmt.Errorf("quad %d: %s outside of a call", i, q.Op)
+			}
+			if q.Result < 0 || int(q.Result) >= len(p.Functions[callee].Params) {
+				return fmt.Errorf("quad %d: %s has no parameter %d", i, p.Functions[callee].Name, q.Result)
+			}
+			if err := assignable(q.Left, p.Functions[callee].Params[q.Result]); err != nil {
+				return fmt.Errorf("quad %d: %s %s", i, q.Op, err)
+			}
+		case GOSUB:
+			if callee < 0 || int(q.Result) != callee {
+				return fmt.Errorf("quad %d: %s without a matching %s", i, q.Op, ERA)
+			}
+			callee = -1
+		case VERIFY:
+			if q.Result <= 0 {
+				return fmt.Errorf("quad %d: invalid length %d", i, q.Result)
+			}
+		case CLEAR:
+			if q.Left == NoAddr || q.Left.Segment() == Const {
+				return fmt.Errorf("quad %d: %s needs a variable address", i, q.Op)
+			}
+			if q.Result <= 0 || q.Left.Index()+int(q.Result) > TypeSpan {
+				return fmt.Errorf("quad %d: invalid count %d", i, q.Result)
+			}
+			if err := p.validAddr(q.Left+q.Result-1, locals, temps); err != nil {
+				return fmt.Errorf("quad %d: %s", i, err)
+			}
+		case RETURN, ENDFUNC:
+			if f == nil {
+				return fmt.Errorf("quad %d: %s outside of a function", i, q.Op)
+			}
+			fallthrough
+		default:
+			if err := p.validAddr(q.Result, locals, temps); err != nil {
+				return fmt.Errorf("quad %d: %s", i, err)
+			}
+		}
+	}
+
+	if callee >= 0 {
+		return fmt.Errorf("call to %s is never made", p.Functions[callee].Name)
+	}
+	for _, i := range jumps {
+		if target := int(p.Quads[i].Result); inCall[target] {
+			return fmt.Errorf("quad %d: jump target %d is inside a call", i, target)
+		}
+	}
+	return nil
+}
+
+// validTypes checks that the operands q reads and writes are present and
+// have the types the VM uses them as
+func validTypes(q Quad) error {
+	switch q.Op {
+	case ADD, SUB, MUL, DIV, NEG:
+		operands := []Addr{q.Left, q.Right}
+		if q.Op == NEG {
+			operands = operands[:1]
+		}
+		if q.Result != NoAddr && q.Result.Type() == semantic.Float {
+			return numeric(operands...)
+		}
+		return expect(semantic.Int, append(operands, q.Result)...)
+	case MOD, BAND, BOR, BXOR, SHL, SHR:
+		return expect(semantic.Int, q.Left, q.Right, q.Result)
+	case BNOT:
+		return expect(semantic.Int, q.Left, q.Result)
+	case LESS_THAN, GREATER_THAN, LESS_EQUAL, GREATER_EQUAL:
+		if err := expect(semantic.Bool, q.Result); err != nil {
+			return err
+		}
+		return numeric(q.Left, q.Right)
+	case EQUAL, NOT_EQUAL:
+		if err := expect(semantic.Bool, q.Result); err != nil {
+			return err
+		}
+		if q.Left != NoAddr && (q.Left.Type() == semantic.Bool || q.Left.Type() == semantic.String) {
+			return expect(q.Left.Type(), q.Right)
+		}
+		return numeric(q.Left, q.Right)
+	case NOT:
+		return expect(semantic.Bool, q.Left, q.Result)
+	case GOTOF, GOTOT:
+		return expect(semantic.Bool, q.Left)
+	case VERIFY, CHECKSTEP:
+		return expect(semantic.Int, q.Left)
+	case LOAD, STORE:
+		if err := expect(semantic.Int, q.Right); err != nil {
+			return err
+		}
+		return assignable(q.Left, q.Result)
+	case ASSIGN, RETURN:
+		return assignable(q.Left, q.Result)
+	case PRINT:
+		return present(q.Left)
+	case CONCAT:
+		if err := present(q.Left, q.Right); err != nil {
+			return err
+		}
+		return expect(semantic.String, q.Result)
+	case LEN:
+		if err := expect(semantic.String, q.Left); err != nil {
+			return err
+		}
+		return expect(semantic.Int, q.Result)
+	}
+	return nil
+}
+
+func present(operands ...Addr) error {
+	for _, a := range operands {
+		if a == NoAddr {
+			return errors.New("is missing an operand")
+		}
+	}
+	return nil
+}
+
+func expect(t semantic.Type, operands ...Addr) error {
+	if err := present(operands...); err != nil {
+		return err
+	}
+	for _, a := range operands {
+		if a.Type() != t {
+			return fmt.Errorf("operand %d is %s, not %s", a, a.Type(), t)
+		}
+	}
+	return nil
+}
+
+// numeric accepts ints and floats, the VM widens ints where it reads floats
+func numeric(operands ...Addr) error {
+	if err := present(operands...); err != nil {
+		return err
+	}
+	for _, a := range operands {
+		if a.Type() != semantic.Int && a.Type() != semantic.Float {
+			return fmt.Errorf("operand %d is %s, not a number", a, a.Type())
+		}
+	}
+	return nil
+}
+
+// assignable reports whether the value at from can be copied to to
+func assignable(from, to Addr) error {
+	if err := present(from, to); err != nil {
+		return err
+	}
+	if from.Type() != to.Type() && (from.Type() != semantic.Int || to.Type() != semantic.Float) {
+		return fmt.Errorf("cannot copy %s %d to %s %d", from.Type(), from, to.Type(), to)
+	}
+	return nil
+}
+
+func (p *Program) validAddr(a Addr, locals, temps Size) error {
+	if a == NoAddr {
+		return nil
+	}
+	if a < 0 {
+		return fmt.Errorf("invalid address %d", a)
+	}
+
+	var size Size
+	switch a.Segment() {
+	case Global:
+		size = p.GlobalSize
+	case Temp:
+		size = temps
+	case Const:
+		c := p.Constants
+		size = Size{Ints: len(c.Ints), Floats: len(c.Floats), Bools: len(c.Bools), Strings: len(c.Strings)}
+	case Local:
+		size = locals
+	default:
+		return fmt.Errorf("address %d is outside of memory", a)
+	}
+
+	if a.Index() >= size.of(a.Type()) {
+		return fmt.Errorf("address %d is outside of the %s %s segment", a, a.Type(), a.Segment())
+	}
+	return nil
+}
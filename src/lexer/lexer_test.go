@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"ciri/src/diag"
 	"ciri/src/token"
 	"testing"
 )
@@ -26,7 +27,7 @@ func TestTokenizeFloatVar(t *testing.T) {
 
 		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
-				i, tok.LineNumber, tt.expectedType, tok.Type)
+				i, tok.Line, tt.expectedType, tok.Type)
 		}
 
 		if tok.Literal != tt.expectedLiteral {
@@ -58,7 +59,7 @@ func TestTokenizeIntVar(t *testing.T) {
 
 		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
-				i, tok.LineNumber, tt.expectedType, tok.Type)
+				i, tok.Line, tt.expectedType, tok.Type)
 		}
 
 		if tok.Literal != tt.expectedLiteral {
@@ -90,7 +91,7 @@ func TestTokenizePrint(t *testing.T) {
 
 		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
-				i, tok.LineNumber, tt.expectedType, tok.Type)
+				i, tok.Line, tt.expectedType, tok.Type)
 		}
 
 		if tok.Literal != tt.expectedLiteral {
@@ -160,7 +161,7 @@ func TestTokenizeConditional(t *testing.T) {
 
 		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
-				i, tok.LineNumber, tt.expectedType, tok.Type)
+				i, tok.Line, tt.expectedType, tok.Type)
 		}
 
 		if tok.Literal != tt.expectedLiteral {
@@ -226,7 +227,7 @@ func TestNextToken(t *testing.T) {
 
 		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
-				i, tok.LineNumber, tt.expectedType, tok.Type)
+				i, tok.Line, tt.expectedType, tok.Type)
 		}
 
 		if tok.Literal != tt.expectedLiteral {
@@ -235,3 +236,622 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "program p:\n  x = \"é\" ñ 10.5;\nend"
+	tests := []struct {
+		expectedLiteral string
+		offset          int
+		endOffset       int
+		line            uint32
+		column          uint32
+	}{
+		{"program", 0, 7, 1, 1},
+		{"p", 8, 9, 1, 9},
+		{":", 9, 10, 1, 10},
+		{"x", 13, 14, 2, 3},
+		{"=", 15, 16, 2, 5},
+		{`"é"`, 17, 21, 2, 7},
+		{"ñ", 22, 24, 2, 11},
+		{"10.5", 25, 29, 2, 13},
+		{";", 29, 30, 2, 17},
+		{"end", 31, 34, 3, 1},
+		{"", 34, 34, 3, 4},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Offset != tt.offset || tok.EndOffset != tt.endOffset {
+			t.Fatalf("tests[%d] - offsets wrong. expected=%d-%d, got=%d-%d",
+				i, tt.offset, tt.endOffset, tok.Offset, tok.EndOffset)
+		}
+
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestTokenizeWhile(t *testing.T) {
+	input := `while (x < 10) { x = x + 1; };`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.WHILE, "while"},
+		{token.OPEN_PARENTHESIS, "("},
+		{token.ID, "x"},
+		{token.LESS_THAN, "<"},
+		{token.INT, "10"},
+		{token.CLOSED_PARENTHESIS, ")"},
+		{token.OPEN_BRACE, "{"},
+		{token.ID, "x"},
+		{token.ASSIGN, "="},
+		{token.ID, "x"},
+		{token.PLUS, "+"},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.CLOSED_BRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeFor(t *testing.T) {
+	input := `for i = 0 to 10 step 2 {};`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FOR, "for"},
+		{token.ID, "i"},
+		{token.ASSIGN, "="},
+		{token.INT, "0"},
+		{token.TO, "to"},
+		{token.INT, "10"},
+		{token.STEP, "step"},
+		{token.INT, "2"},
+		{token.OPEN_BRACE, "{"},
+		{token.CLOSED_BRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeFunction(t *testing.T) {
+	input := `func f(a: int): int { return f(a); }`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FUNC, "func"},
+		{token.ID, "f"},
+		{token.OPEN_PARENTHESIS, "("},
+		{token.ID, "a"},
+		{token.COLON, ":"},
+		{token.INT_TYPE, "int"},
+		{token.CLOSED_PARENTHESIS, ")"},
+		{token.COLON, ":"},
+		{token.INT_TYPE, "int"},
+		{token.OPEN_BRACE, "{"},
+		{token.RETURN, "return"},
+		{token.ID, "f"},
+		{token.OPEN_PARENTHESIS, "("},
+		{token.ID, "a"},
+		{token.CLOSED_PARENTHESIS, ")"},
+		{token.SEMICOLON, ";"},
+		{token.CLOSED_BRACE, "}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeArrays(t *testing.T) {
+	input := `grid[4][i]: int;`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ID, "grid"},
+		{token.OPEN_BRACKET, "["},
+		{token.INT, "4"},
+		{token.CLOSED_BRACKET, "]"},
+		{token.OPEN_BRACKET, "["},
+		{token.ID, "i"},
+		{token.CLOSED_BRACKET, "]"},
+		{token.COLON, ":"},
+		{token.INT_TYPE, "int"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeLogical(t *testing.T) {
+	input := `ok: bool; ok = !true && false || ok; & |`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ID, "ok"},
+		{token.COLON, ":"},
+		{token.BOOL_TYPE, "bool"},
+		{token.SEMICOLON, ";"},
+		{token.ID, "ok"},
+		{token.ASSIGN, "="},
+		{token.NOT, "!"},
+		{token.TRUE, "true"},
+		{token.AND, "&&"},
+		{token.FALSE, "false"},
+		{token.OR, "||"},
+		{token.ID, "ok"},
+		{token.SEMICOLON, ";"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeComparisons(t *testing.T) {
+	input := `a == b != c <= d >= e <> f < g > h <== i = !j`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ID, "a"},
+		{token.EQUAL, "=="},
+		{token.ID, "b"},
+		{token.NOT_EQUAL, "!="},
+		{token.ID, "c"},
+		{token.LESS_EQUAL, "<="},
+		{token.ID, "d"},
+		{token.GREATER_EQUAL, ">="},
+		{token.ID, "e"},
+		{token.LESS_THEN_GREAT, "<>"},
+		{token.ID, "f"},
+		{token.LESS_THAN, "<"},
+		{token.ID, "g"},
+		{token.GREATER_THAN, ">"},
+		{token.ID, "h"},
+		{token.LESS_EQUAL, "<="},
+		{token.ASSIGN, "="},
+		{token.ID, "i"},
+		{token.ASSIGN, "="},
+		{token.NOT, "!"},
+		{token.ID, "j"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.EndOffset-tok.Offset != len(tt.expectedLiteral) {
+			t.Fatalf("tests[%d] - span wrong. expected=%d bytes, got=%d",
+				i, len(tt.expectedLiteral), tok.EndOffset-tok.Offset)
+		}
+	}
+}
+
+func TestTokenizeBitwise(t *testing.T) {
+	input := `a % b & c | d ^ ~e << 2 >> f && g || h <<= i`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ID, "a"},
+		{token.MODULO, "%"},
+		{token.ID, "b"},
+		{token.BIT_AND, "&"},
+		{token.ID, "c"},
+		{token.BIT_OR, "|"},
+		{token.ID, "d"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.ID, "e"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.INT, "2"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.ID, "f"},
+		{token.AND, "&&"},
+		{token.ID, "g"},
+		{token.OR, "||"},
+		{token.ID, "h"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.ASSIGN, "="},
+		{token.ID, "i"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeStrings(t *testing.T) {
+	input := `msg: string; msg = "id=" + 4; n = len(msg);`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ID, "msg"},
+		{token.COLON, ":"},
+		{token.STRING_TYPE, "string"},
+		{token.SEMICOLON, ";"},
+		{token.ID, "msg"},
+		{token.ASSIGN, "="},
+		{token.STRING, `"id="`},
+		{token.PLUS, "+"},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.ID, "n"},
+		{token.ASSIGN, "="},
+		{token.ID, "len"},
+		{token.OPEN_PARENTHESIS, "("},
+		{token.ID, "msg"},
+		{token.CLOSED_PARENTHESIS, ")"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong at line=%d . expected=%q, got=%q",
+				i, tok.Line, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenizeStringEscapes(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{`"plain"`, "plain"},
+		{`"say \"hi\"\n"`, "say \"hi\"\n"},
+		{`"a\tb\\c\r"`, "a\tb\\c\r"},
+		{`"\x41\x7e\xFF"`, "A~\xff"},
+		{`"\u{48}\u{e9}\u{1F600}"`, "Hé\U0001F600"},
+		{"`raw \\n \"quotes\"`", `raw \n "quotes"`},
+		{`""`, ""},
+		{"\"two\nlines\"", "two\nlines"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.STRING, tok.Type)
+		}
+		if tok.Literal != tt.input {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.input, tok.Literal)
+		}
+		if tok.Value != tt.value {
+			t.Fatalf("tests[%d] - value wrong. expected=%q, got=%q", i, tt.value, tok.Value)
+		}
+		if len(l.Diagnostics()) != 0 {
+			t.Fatalf("tests[%d] - unexpected diagnostics: %s", i, l.Diagnostics())
+		}
+	}
+}
+
+func TestTokenizeMultiLineStrings(t *testing.T) {
+	input := "x = `first\nsecond\nthird`; y = \"a\nb\" + z;"
+	tests := []struct {
+		expectedType token.Type
+		line         uint32
+		column       uint32
+	}{
+		{token.ID, 1, 1},
+		{token.ASSIGN, 1, 3},
+		{token.STRING, 1, 5},
+		{token.SEMICOLON, 3, 7},
+		{token.ID, 3, 9},
+		{token.ASSIGN, 3, 11},
+		{token.STRING, 3, 13},
+		{token.PLUS, 4, 4},
+		{token.ID, 4, 6},
+		{token.SEMICOLON, 4, 7},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - token wrong. expected=%q at %d:%d, got=%q at %d:%d",
+				i, tt.expectedType, tt.line, tt.column, tok.Type, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestTokenizeInvalidEscapes(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		line    uint32
+		column  uint32
+	}{
+		{`"ok \q"`, `unknown escape sequence \q`, 1, 5},
+		{"\"line\n  \\x4g\"", `invalid escape sequence \x4, \x needs 2 hex digits`, 2, 3},
+		{`"\u00e9"`, `invalid escape sequence \u, expected \u{...}`, 1, 2},
+		{`"é\u{110000}"`, `invalid escape sequence \u{110000, expected a unicode code point`, 1, 3},
+		{`"\u{}"`, `invalid escape sequence \u{, expected a unicode code point`, 1, 2},
+		{`"\é"`, `unknown escape sequence \é`, 1, 2},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.STRING, tok.Type)
+		}
+		diagnostics := l.Diagnostics()
+		if len(diagnostics) != 1 {
+			t.Fatalf("tests[%d] - expected 1 diagnostic, got=%d", i, len(diagnostics))
+		}
+		d := diagnostics[0]
+		if d.Code != diag.InvalidEscape || d.Message != tt.message {
+			t.Fatalf("tests[%d] - diagnostic wrong. expected=%q, got=%q (%s)", i, tt.message, d.Message, d.Code)
+		}
+		if d.Pos.Line != tt.line || d.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s", i, tt.line, tt.column, d.Pos)
+		}
+	}
+}
+
+func TestTokenizeComments(t *testing.T) {
+	input := "x = a / b; // divide\n" +
+		"/* block\n   /* nested */ still comment */ y = 1 /**/ * 2;\n" +
+		"z = \"// not a comment\"; // last"
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		line            uint32
+	}{
+		{token.ID, "x", 1},
+		{token.ASSIGN, "=", 1},
+		{token.ID, "a", 1},
+		{token.DIVIDE, "/", 1},
+		{token.ID, "b", 1},
+		{token.SEMICOLON, ";", 1},
+		{token.ID, "y", 3},
+		{token.ASSIGN, "=", 3},
+		{token.INT, "1", 3},
+		{token.MULTIPLY, "*", 3},
+		{token.INT, "2", 3},
+		{token.SEMICOLON, ";", 3},
+		{token.ID, "z", 4},
+		{token.ASSIGN, "=", 4},
+		{token.STRING, `"// not a comment"`, 4},
+		{token.SEMICOLON, ";", 4},
+		{token.EOF, "", 4},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Line != tt.line {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q at line %d, got=%q %q at line %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.line, tok.Type, tok.Literal, tok.Line)
+		}
+	}
+
+	comments := []struct {
+		literal string
+		line    uint32
+		column  uint32
+	}{
+		{"// divide", 1, 12},
+		{"/* block\n   /* nested */ still comment */", 2, 1},
+		{"/**/", 3, 40},
+		{"// last", 4, 25},
+	}
+	if len(l.Comments()) != len(comments) {
+		t.Fatalf("comments wrong. expected=%d, got=%d", len(comments), len(l.Comments()))
+	}
+	for i, c := range comments {
+		tok := l.Comments()[i]
+		if tok.Type != token.COMMENT || tok.Literal != c.literal || tok.Line != c.line || tok.Column != c.column {
+			t.Fatalf("comments[%d] - wrong. expected=%q at %d:%d, got=%q %q at %d:%d",
+				i, c.literal, c.line, c.column, tok.Type, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+	if len(l.Diagnostics()) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", l.Diagnostics())
+	}
+}
+
+func TestTokenizeUnterminatedComment(t *testing.T) {
+	l := New("x = 1;\n  /* outer /* inner */\n y = 2;")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Line != 1 {
+			t.Fatalf("token %q should be inside the comment", tok.Literal)
+		}
+	}
+
+	diagnostics := l.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%d", len(diagnostics))
+	}
+	d := diagnostics[0]
+	if d.Code != diag.OpenComment || d.Message != "comment not terminated" || d.Pos.Line != 2 || d.Pos.Column != 3 {
+		t.Fatalf("diagnostic wrong, got=%s (%s)", d, d.Code)
+	}
+}
+
+func TestTokenizeUnterminatedString(t *testing.T) {
+	l := New("x = 1;\n  y = \"never closed \\\"\n;")
+	var last token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		last = tok
+	}
+	if last.Type != token.STRING || last.Value != "never closed \"\n;" {
+		t.Fatalf("unclosed string should end with the input, got=%q %q", last.Type, last.Value)
+	}
+
+	diagnostics := l.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%d", len(diagnostics))
+	}
+	d := diagnostics[0]
+	if d.Code != diag.OpenString || d.Message != "string not terminated" || d.Pos.Line != 2 || d.Pos.Column != 7 {
+		t.Fatalf("diagnostic wrong, got=%s (%s)", d, d.Code)
+	}
+}
+
+func TestTokenizeNumbers(t *testing.T) {
+	input := `0x1F 0b1010 1_000_000 1.5e-3 007 1.2.3 0x1e+5 2e+3-1 0b12 x=3.0;`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.INT, "0x1F"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "1.5e-3"},
+		{token.INT, "007"},
+		{token.ILLEGAL, "1.2.3"},
+		{token.INT, "0x1e"},
+		{token.PLUS, "+"},
+		{token.INT, "5"},
+		{token.FLOAT, "2e+3"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.ILLEGAL, "0b12"},
+		{token.ID, "x"},
+		{token.ASSIGN, "="},
+		{token.FLOAT, "3.0"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q (%q)",
+				i, tt.expectedType, tok.Type, tok.Literal)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.EndOffset-tok.Offset != len(tt.expectedLiteral) {
+			t.Fatalf("tests[%d] - span wrong. expected=%d bytes, got=%d",
+				i, len(tt.expectedLiteral), tok.EndOffset-tok.Offset)
+		}
+	}
+}
@@ -0,0 +1,548 @@
+package semantic
+
+import (
+	"ciri/src/ast"
+	"ciri/src/diag"
+	"ciri/src/token"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Info is the result of checking a program
+type Info struct {
+	Globals   *SymbolTable
+	Functions map[string]*Function
+	// Types holds the type of every checked expression
+	Types map[ast.Expression]Type
+	// Idents holds the symbol every identifier resolves to
+	Idents map[*ast.Ident]*Symbol
+	// Warnings are reported even when the program is valid
+	Warnings diag.DiagnosticList
+}
+
+type checker struct {
+	info  *Info
+	scope *SymbolTable
+	// function is the function being checked, nil in the main block
+	function    *Function
+	diagnostics diag.DiagnosticList
+}
+
+// Check walks the program, building its symbol table and reporting
+// undeclared identifiers, duplicate declarations and type mismatches,
+// the error is a diag.DiagnosticList
+func Check(program *ast.Program) (*Info, error) {
+	c := &checker{
+		info: &Info{
+			Globals:   NewSymbolTable(),
+			Functions: make(map[string]*Function),
+			Types:     make(map[ast.Expression]Type),
+			Idents:    make(map[*ast.Ident]*Symbol),
+		},
+	}
+	c.scope = c.info.Globals
+
+	// every signature is known before any body or initializer is checked
+	// so functions can call the ones declared after them
+	functions := make([]*Function, len(program.Funcs))
+	for i, f := range program.Funcs {
+		functions[i] = c.signature(f)
+	}
+
+	c.declare(program.Vars)
+
+	for i, f := range program.Funcs {
+		c.functionBody(f, functions[i])
+	}
+
+	c.block(program.Body)
+
+	return c.info, c.diagnostics.Err()
+}
+
+func (c *checker) errorf(code diag.Code, tok token.Token, format string, args ...interface{}) *diag.Diagnostic {
+	d := diag.Errorf(code, tok, format, args...)
+	c.diagnostics.Add(d)
+	return d
+}
+
+func (c *checker) warnf(code diag.Code, tok token.Token, format string, args ...interface{}) *diag.Diagnostic {
+	d := diag.Warningf(code, tok, format, args...)
+	c.diagnostics.Add(d)
+	c.info.Warnings.Add(d)
+	return d
+}
+
+// Declarations
+
+func (c *checker) declare(decls []*ast.VarDecl) {
+	for _, decl := range decls {
+		t := TypeOf(decl.Type.Type)
+		if decl.Value != nil {
+			t = c.initializer(decl, t)
+		}
+		for i, name := range decl.Names {
+			symbol := c.define(name, t)
+			if symbol != nil && i < len(decl.Dims) {
+				symbol.Dims = c.dimensions(name, decl.Dims[i])
+			}
+		}
+	}
+}
+
+// initializer checks the value of a declaration and returns the type of
+// the variable, it is the type of the value when none is written. The value
+// is checked before the name is defined so it cannot refer to itself.
+func (c *checker) initializer(decl *ast.VarDecl, t Type) Type {
+	value := c.expression(decl.Value)
+	name := decl.Names[0]
+	switch {
+	case len(decl.Names) > 1:
+		c.errorf(diag.TypeMismatch, decl.Value.Pos(), "assignment mismatch: %d variables but 1 value", len(decl.Names))
+	case len(decl.Dims) > 0 && len(decl.Dims[0]) > 0:
+		c.errorf(diag.TypeMismatch, decl.Value.Pos(), "cannot initialize array %s", name.Name)
+	case decl.Type.Type == "":
+		return value
+	case value != Invalid && !Assignable(t, value):
+		c.errorf(diag.TypeMismatch, decl.Value.Pos(), "cannot use %s as %s value in declaration of %s", value, t, name.Name)
+	}
+	return t
+}
+
+// dimensions evaluates the dimensions of an array declaration, they must be
+// positive int constants. Invalid ones are reported and taken as 1 so the
+// name still resolves to an array.
+func (c *checker) dimensions(name *ast.Ident, dims []ast.Expression) []int {
+	if len(dims) == 0 {
+		return nil
+	}
+	if len(dims) > MaxDims {
+		c.errorf(diag.InvalidIndex, name.Token, "%s has %d dimensions, arrays have at most %d", name.Name, len(dims), MaxDims)
+	}
+
+	sizes := make([]int, len(dims))
+	total := int64(1)
+	for i, dim := range dims {
+		v, ok := IntConstant(dim)
+		c.expression(dim)
+		switch {
+		case !ok:
+			c.errorf(diag.InvalidIndex, dim.Pos(), "array length of %s must be an int constant", name.Name)
+			v = 1
+		case v <= 0:
+			c.errorf(diag.InvalidIndex, dim.Pos(), "invalid array length %d for %s", v, name.Name)
+			v = 1
+		case v > math.MaxInt32/total:
+			c.errorf(diag.InvalidIndex, dim.Pos(), "array %s is too large", name.Name)
+			v = 1
+		}
+		total *= v
+		sizes[i] = int(v)
+	}
+	return sizes
+}
+
+func (c *checker) define(name *ast.Ident, t Type) *Symbol {
+	symbol, ok := c.scope.Define(name.Name, t, name.Token)
+	if !ok {
+		c.redeclared(name, symbol.Name, symbol.Token)
+		return nil
+	}
+	c.info.Idents[name] = symbol
+	return symbol
+}
+
+func (c *checker) redeclared(name *ast.Ident, existing string, previous token.Token) {
+	d := c.errorf(diag.Redeclared, name.Token, "%s redeclared", name.Name)
+	d.Notes = append(d.Notes, diag.Note{
+		Pos:     diag.PosOf(previous),
+		Message: "previous declaration of " + existing,
+	})
+}
+
+// signature declares a function and its parameters
+func (c *checker) signature(f *ast.Function) *Function {
+	fn := &Function{
+		Name:   f.Name.Name,
+		Result: Void,
+		Scope:  NewEnclosedSymbolTable(c.info.Globals),
+		Token:  f.Name.Token,
+	}
+	if f.Result.Type != "" {
+		fn.Result = TypeOf(f.Result.Type)
+	}
+
+	if existing, ok := c.info.Functions[fn.Name]; ok {
+		c.redeclared(f.Name, existing.Name, existing.Token)
+	} else {
+		c.info.Functions[fn.Name] = fn
+	}
+
+	c.scope = fn.Scope
+	for _, param := range f.Params {
+		if symbol := c.define(param.Name, TypeOf(param.Type.Type)); symbol != nil {
+			fn.Params = append(fn.Params, symbol)
+		}
+	}
+	c.scope = c.info.Globals
+
+	return fn
+}
+
+// functionBody checks the locals and statements of f in its own scope
+func (c *checker) functionBody(f *ast.Function, fn *Function) {
+	c.scope, c.function = fn.Scope, fn
+	defer func() { c.scope, c.function = c.info.Globals, nil }()
+
+	c.declare(f.Vars)
+	c.block(f.Body)
+
+	if fn.Result != Void && !terminates(f.Body) {
+		c.errorf(diag.MissingReturn, f.Name.Token, "missing return at end of function %s", fn.Name)
+	}
+}
+
+// terminates reports whether every path through b ends in a return
+func terminates(b *ast.Block) bool {
+	if b == nil || len(b.Statements) == 0 {
+		return false
+	}
+	switch s := b.Statements[len(b.Statements)-1].(type) {
+	case *ast.Return:
+		return true
+	case *ast.If:
+		return terminates(s.Consequence) && terminates(s.Alternative)
+	default:
+		return false
+	}
+}
+
+// Statements
+
+// block checks b in a scope of its own for the variables it declares
+func (c *checker) block(b *ast.Block) {
+	if b == nil {
+		return
+	}
+	outer := c.scope
+	c.scope = NewEnclosedSymbolTable(outer)
+	defer func() { c.scope = outer }()
+
+	for _, stmt := range b.Statements {
+		c.statement(stmt)
+	}
+}
+
+// shadowing warns about block variables that hide an outer one
+func (c *checker) shadowing(decl *ast.VarDecl) {
+	for _, name := range decl.Names {
+		if c.info.Idents[name] == nil {
+			continue
+		}
+		if outer, ok := c.scope.Outer.Resolve(name.Name); ok {
+			d := c.warnf(diag.Shadowed, name.Token, "declaration of %s shadows an outer variable", name.Name)
+			d.Notes = append(d.Notes, diag.Note{
+				Pos:     diag.PosOf(outer.Token),
+				Message: "shadowed declaration of " + outer.Name,
+			})
+		}
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.Assign:
+		c.assign(s)
+	case *ast.If:
+		c.condition(s.Condition, "if")
+		c.block(s.Consequence)
+		c.block(s.Alternative)
+	case *ast.While:
+		c.condition(s.Condition, "while")
+		c.block(s.Body)
+	case *ast.For:
+		c.forLoop(s)
+	case *ast.Print:
+		for _, arg := range s.Args {
+			c.expression(arg)
+		}
+	case *ast.Return:
+		c.returnStmt(s)
+	case *ast.CallStmt:
+		c.info.Types[s.Call] = c.call(s.Call)
+	case *ast.DeclStmt:
+		c.declare([]*ast.VarDecl{s.Decl})
+		c.shadowing(s.Decl)
+	}
+}
+
+func (c *checker) returnStmt(r *ast.Return) {
+	var value Type
+	if r.Value != nil {
+		value = c.expression(r.Value)
+	}
+
+	switch {
+	case c.function == nil:
+		c.errorf(diag.InvalidReturn, r.Token, "return outside of a function")
+	case c.function.Result == Void && r.Value != nil:
+		c.errorf(diag.InvalidReturn, r.Value.Pos(), "too many return values in %s", c.function.Name)
+	case c.function.Result != Void && r.Value == nil:
+		c.errorf(diag.InvalidReturn, r.Token, "missing return value in %s (type %s)", c.function.Name, c.function.Result)
+	case r.Value != nil && value != Invalid && !Assignable(c.function.Result, value):
+		c.errorf(diag.TypeMismatch, r.Value.Pos(), "cannot return %s from %s (type %s)", value, c.function.Name, c.function.Result)
+	}
+}
+
+// condition checks that the condition of a statement is a bool
+func (c *checker) condition(cond ast.Expression, statement string) {
+	t := c.expression(cond)
+	if t != Invalid && t != Bool {
+		c.errorf(diag.TypeMismatch, cond.Pos(), "non-boolean condition in %s statement (type %s)", statement, t)
+	}
+}
+
+// forLoop checks that the loop variable and its range are ints, a step that
+// is known to be zero is rejected since the loop would never end
+func (c *checker) forLoop(f *ast.For) {
+	if t := c.scalar(f.Var); t != Invalid && t != Int {
+		c.errorf(diag.TypeMismatch, f.Var.Token, "for loop variable %s must be int (type %s)", f.Var.Name, t)
+	}
+
+	bounds := []struct {
+		name string
+		expr ast.Expression
+	}{{"start", f.Start}, {"end", f.End}, {"step", f.Step}}
+
+	for _, bound := range bounds {
+		if bound.expr == nil {
+			continue
+		}
+		if t := c.expression(bound.expr); t != Invalid && t != Int {
+			c.errorf(diag.TypeMismatch, bound.expr.Pos(), "for loop %s must be int (type %s)", bound.name, t)
+		}
+	}
+
+	if f.Step != nil {
+		if step, ok := IntConstant(f.Step); ok && step == 0 {
+			c.errorf(diag.InvalidStep, f.Step.Pos(), "for loop step cannot be zero")
+		}
+	}
+
+	c.block(f.Body)
+}
+
+func (c *checker) assign(a *ast.Assign) {
+	value := c.expression(a.Value)
+	target := c.expression(a.Target)
+
+	if target == Invalid || value == Invalid {
+		return
+	}
+	if !Assignable(target, value) {
+		c.errorf(diag.TypeMismatch, a.Value.Pos(), "cannot assign %s to %s (type %s)", value, a.Target, target)
+	}
+}
+
+// Expressions
+
+func (c *checker) expression(expr ast.Expression) Type {
+	var t Type
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		t = c.scalar(e)
+	case *ast.Index:
+		t = c.index(e)
+	case *ast.Literal:
+		t = c.literal(e)
+	case *ast.UnaryExpr:
+		if l, ok := SignedLiteral(e); ok {
+			// the sign is part of the value, -9223372036854775808 fits
+			t = c.literal(l)
+			break
+		}
+		t = c.unary(e)
+	case *ast.BinaryExpr:
+		t = c.binary(e)
+	case *ast.Call:
+		t = c.call(e)
+		if t == Void {
+			c.errorf(diag.InvalidCall, e.Token, "%s (no value) used as value", e)
+			t = Invalid
+		}
+	}
+
+	c.info.Types[expr] = t
+	return t
+}
+
+// literal checks that a number fits in its type
+func (c *checker) literal(l *ast.Literal) Type {
+	t := TypeOf(l.Token.Type)
+	var err error
+	switch t {
+	case Int:
+		_, err = IntValue(l.Token.Literal)
+	case Float:
+		_, err = FloatValue(l.Token.Literal)
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		c.errorf(diag.InvalidConst, l.Token, "%s literal %s overflows %s", t, l.Token.Literal, t)
+	} else if err != nil {
+		c.errorf(diag.InvalidConst, l.Token, "invalid %s literal %s", t, l.Token.Literal)
+	}
+	return t
+}
+
+func (c *checker) ident(i *ast.Ident) Type {
+	symbol, ok := c.scope.Resolve(i.Name)
+	if !ok {
+		c.errorf(diag.Undeclared, i.Token, "undeclared identifier %s", i.Name)
+		c.info.Types[i] = Invalid
+		return Invalid
+	}
+	c.info.Types[i] = symbol.Type
+	c.info.Idents[i] = symbol
+	return symbol.Type
+}
+
+// scalar resolves an identifier used as a single value
+func (c *checker) scalar(i *ast.Ident) Type {
+	t := c.ident(i)
+	if symbol := c.info.Idents[i]; symbol != nil && symbol.Dims != nil {
+		c.errorf(diag.InvalidIndex, i.Token, "array %s used without an index", i.Name)
+		return Invalid
+	}
+	return t
+}
+
+// index checks an element access against the dimensions of the array,
+// constant indices are checked against its bounds
+func (c *checker) index(e *ast.Index) Type {
+	t := c.ident(e.Array)
+	for _, index := range e.Indices {
+		if it := c.expression(index); it != Invalid && it != Int {
+			c.errorf(diag.TypeMismatch, index.Pos(), "array index must be int (type %s)", it)
+		}
+	}
+
+	symbol := c.info.Idents[e.Array]
+	switch {
+	case symbol == nil:
+		return Invalid
+	case symbol.Dims == nil:
+		c.errorf(diag.InvalidIndex, e.Token, "cannot index %s (type %s)", e.Array.Name, t)
+		return Invalid
+	case len(e.Indices) != len(symbol.Dims):
+		c.errorf(diag.InvalidIndex, e.Token, "%s has %d dimensions, got %d indices", e.Array.Name, len(symbol.Dims), len(e.Indices))
+		return Invalid
+	}
+
+	for i, index := range e.Indices {
+		if v, ok := IntConstant(index); ok && (v < 0 || v >= int64(symbol.Dims[i])) {
+			c.errorf(diag.InvalidIndex, index.Pos(), "index %d out of bounds for %s (length %d)", v, e.Array.Name, symbol.Dims[i])
+		}
+	}
+	return t
+}
+
+// call checks the arguments against the signature of the function and
+// returns its result type
+func (c *checker) call(call *ast.Call) Type {
+	args := make([]Type, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = c.expression(arg)
+	}
+
+	fn, ok := c.info.Functions[call.Function.Name]
+	if !ok && call.Function.Name == Len {
+		return c.length(call, args)
+	}
+	if !ok {
+		c.errorf(diag.Undeclared, call.Token, "undeclared function %s", call.Function.Name)
+		return Invalid
+	}
+
+	if len(args) != len(fn.Params) {
+		problem := "not enough"
+		if len(args) > len(fn.Params) {
+			problem = "too many"
+		}
+		c.errorf(diag.InvalidCall, call.Token, "%s arguments in call to %s (expected %d, got %d)",
+			problem, fn.Name, len(fn.Params), len(args))
+		return fn.Result
+	}
+
+	for i, arg := range args {
+		param := fn.Params[i]
+		if arg != Invalid && !Assignable(param.Type, arg) {
+			c.errorf(diag.TypeMismatch, call.Args[i].Pos(), "cannot use %s as %s in argument %s of %s",
+				arg, param.Type, param.Name, fn.Name)
+		}
+	}
+	return fn.Result
+}
+
+// length checks a call to the len builtin
+func (c *checker) length(call *ast.Call, args []Type) Type {
+	if len(args) != 1 {
+		problem := "not enough"
+		if len(args) > 1 {
+			problem = "too many"
+		}
+		c.errorf(diag.InvalidCall, call.Token, "%s arguments in call to %s (expected 1, got %d)", problem, Len, len(args))
+	} else if args[0] != Invalid && args[0] != String {
+		c.errorf(diag.TypeMismatch, call.Args[0].Pos(), "invalid argument %s (type %s) for %s", call.Args[0], args[0], Len)
+	}
+	return Int
+}
+
+func (c *checker) unary(u *ast.UnaryExpr) Type {
+	operand := c.expression(u.Operand)
+	if operand == Invalid {
+		return Invalid
+	}
+	valid := numeric(operand)
+	switch u.Operator {
+	case token.NOT:
+		valid = operand == Bool
+	case token.BIT_NOT:
+		valid = operand == Int
+	}
+	if !valid {
+		c.errorf(diag.TypeMismatch, u.Token, "invalid operation: %s%s (operator %s not defined on %s)",
+			u.Operator, u.Operand, u.Operator, operand)
+		return Invalid
+	}
+	return operand
+}
+
+func (c *checker) binary(b *ast.BinaryExpr) Type {
+	left := c.expression(b.Left)
+	right := c.expression(b.Right)
+	if left == Invalid || right == Invalid {
+		return Invalid
+	}
+
+	t := Result(b.Operator, left, right)
+	if t != Invalid {
+		return t
+	}
+	// numeric operands always mix, so they failed because of the operator
+	if left == right || numeric(left) && numeric(right) {
+		operand := left
+		if Result(b.Operator, left, left) != Invalid {
+			operand = right
+		}
+		c.errorf(diag.TypeMismatch, b.Token, "invalid operation: %s (operator %s not defined on %s)", b, b.Operator, operand)
+	} else {
+		c.errorf(diag.TypeMismatch, b.Token, "invalid operation: %s (mismatched types %s and %s)", b, left, right)
+	}
+	return Invalid
+}
+
+func numeric(t Type) bool {
+	return t == Int || t == Float
+}
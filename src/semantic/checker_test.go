@@ -0,0 +1,656 @@
+package semantic
+
+import (
+	"ciri/src/ast"
+	"ciri/src/diag"
+	"ciri/src/goyacc"
+	"strings"
+	"testing"
+)
+
+func check(t *testing.T, input string) (*Info, error) {
+	program, err := goyacc.Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return Check(program)
+}
+
+func expectErrors(t *testing.T, err error, expected ...string) {
+	if err == nil {
+		t.Fatalf("expected %d errors, got none", len(expected))
+	}
+
+	errs, ok := err.(diag.DiagnosticList)
+	if !ok {
+		t.Fatalf("expected diag.DiagnosticList, got=%T", err)
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got=%d\n%s", len(expected), len(errs), err)
+	}
+
+	for i, e := range errs {
+		if !strings.Contains(e.Message, expected[i]) {
+			t.Fatalf("errors[%d] - expected %q in %q", i, expected[i], e.Message)
+		}
+	}
+}
+
+func TestCheckValidProgram(t *testing.T) {
+	input := `
+		program testRun : var x, y: int; z, f: float; {
+			x = 10;
+			y = 11;
+			z = 100.2;
+			f = z + y + x;
+			f = x;
+
+			if (x + 10.35 > 100) {
+				print(x + 10);
+			} else {
+				print("this is legal", -z);
+			};
+		}
+	`
+	info, err := check(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	symbol, ok := info.Globals.Resolve("z")
+	if !ok || symbol.Type != Float {
+		t.Fatalf("z should be declared as float, got=%v", symbol)
+	}
+}
+
+func TestCheckUndeclaredIdentifier(t *testing.T) {
+	input := `
+		program test: var x, d : int;  {
+			x = 10;
+			x = 20 + 50 + ty;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err, "undeclared identifier ty")
+
+	d := err.(diag.DiagnosticList)[0]
+	if d.Pos.Line != 4 || d.Code != diag.Undeclared {
+		t.Fatalf("diagnostic wrong. expected line=%d code=%s, got=%d %s", 4, diag.Undeclared, d.Pos.Line, d.Code)
+	}
+}
+
+func TestCheckUndeclaredAssignTarget(t *testing.T) {
+	input := `
+		program test: {
+			m = 10;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err, "undeclared identifier m")
+}
+
+func TestCheckDuplicateDeclaration(t *testing.T) {
+	input := `
+		program test: var x, y: int; x: float; {
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err, "x redeclared")
+
+	d := err.(diag.DiagnosticList)[0]
+	if len(d.Notes) != 1 || d.Notes[0].Pos.Line != 2 {
+		t.Fatalf("expected a note at the previous declaration, got=%v", d.Notes)
+	}
+}
+
+func TestCheckTypeMismatch(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; {
+			x = 10.5;
+			x = x + f;
+			f = (x > 1) + 2;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"cannot assign float to x",
+		"cannot assign float to x",
+		"mismatched types bool and int",
+	)
+}
+
+func TestCheckExpressionTypes(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; {
+			print(x * 2, x / f, x < f, "s");
+		}
+	`
+	program, err := goyacc.Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	info, err := Check(program)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	stmt := program.Body.Statements[0].(*ast.Print)
+	expected := []Type{Int, Float, Bool, String}
+	for i, arg := range stmt.Args {
+		if info.Types[arg] != expected[i] {
+			t.Fatalf("args[%d] - type wrong. expected=%s, got=%s", i, expected[i], info.Types[arg])
+		}
+	}
+}
+
+func TestCheckFor(t *testing.T) {
+	input := `
+		program test: var i: int; f: float; {
+			for i = 0 to 10 step 2 {};
+			for i = 10 to 0 step -1 {};
+			for f = 0 to 10 {};
+			for i = 0.5 to 10 {};
+			for i = 0 to 10 step 0 {};
+			for i = 0 to 10 step 1.5 {};
+			for k = 0 to 10 {};
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"for loop variable f must be int (type float)",
+		"for loop start must be int (type float)",
+		"for loop step cannot be zero",
+		"for loop step must be int (type float)",
+		"undeclared identifier k",
+	)
+}
+
+func TestCheckWhileCondition(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			while (x < 10) {
+				x = x + 1;
+			};
+			while (x + 1) {
+				y = 2;
+			};
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"non-boolean condition in while statement (type int)",
+		"undeclared identifier y",
+	)
+}
+
+func TestCheckFunctions(t *testing.T) {
+	input := `
+		program test: var x: int; f: float;
+		func fact(n: int): int {
+			if (n < 2) {
+				return 1;
+			} else {
+				return n * fact(n - 1);
+			};
+		}
+		func scale(v: float, by: int): float {
+			var result: float;
+			result = v * by + x;
+			return result;
+		}
+		func show(n: int) {
+			print(n, isEven(n));
+			return;
+		}
+		func isEven(n: int): int {
+			return n - n / 2 * 2;
+		}
+		{
+			x = fact(5);
+			f = scale(x, 2);
+			show(fact(3));
+			isEven(4);
+		}
+	`
+	info, err := check(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	fn, ok := info.Functions["scale"]
+	if !ok || fn.Result != Float || len(fn.Params) != 2 || fn.Params[1].Type != Int {
+		t.Fatalf("scale signature wrong, got=%v", fn)
+	}
+	if _, ok := fn.Scope.Resolve("result"); !ok {
+		t.Fatalf("result should be declared in the scope of scale")
+	}
+	if _, ok := info.Globals.Resolve("result"); ok {
+		t.Fatalf("locals should not leak into the globals")
+	}
+	if info.Functions["show"].Result != Void {
+		t.Fatalf("show should return nothing")
+	}
+}
+
+func TestCheckCallErrors(t *testing.T) {
+	input := `
+		program test: var x: int;
+		func add(a: int, b: int): int {
+			return a + b;
+		}
+		func show(n: float) {
+			print(n);
+		}
+		{
+			x = add(1);
+			x = add(1, 2, 3);
+			x = add(1, 2.5);
+			x = show(1.5);
+			x = missing(1);
+			show(x);
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"not enough arguments in call to add (expected 2, got 1)",
+		"too many arguments in call to add (expected 2, got 3)",
+		"cannot use float as int in argument b of add",
+		"show(1.5) (no value) used as value",
+		"undeclared function missing",
+	)
+}
+
+func TestCheckReturnErrors(t *testing.T) {
+	input := `
+		program test: var x: int;
+		func a(): int {
+			return 1.5;
+		}
+		func b(): int {
+			return;
+		}
+		func c() {
+			return 1;
+		}
+		func d(): int {
+			if (x > 1) {
+				return 1;
+			};
+		}
+		func a(y: int, y: float) {}
+		{
+			return;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"a redeclared",
+		"y redeclared",
+		"cannot return float from a (type int)",
+		"missing return value in b (type int)",
+		"too many return values in c",
+		"missing return at end of function d",
+		"return outside of a function",
+	)
+}
+
+func TestCheckArrays(t *testing.T) {
+	input := `
+		program test: var readings[16]: float; grid[4][4], i: int; {
+			readings[i] = grid[i][3] + 0.5;
+			grid[3][0] = i;
+			print(readings[15]);
+		}
+	`
+	info, err := check(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	grid, _ := info.Globals.Resolve("grid")
+	if grid.Type != Int || len(grid.Dims) != 2 || grid.Size() != 16 {
+		t.Fatalf("grid wrong, got=%+v", grid)
+	}
+}
+
+func TestCheckArrayErrors(t *testing.T) {
+	input := `
+		program test: var a[4]: int; m[2][3]: float; x: int; z[0]: int; n[x]: int; c[2][2][2]: int; {
+			a[4] = 1;
+			x = a[-1];
+			x = a[~0];
+			m[1][3] = 1.5;
+			x = a[1.5];
+			x = a;
+			x = x[1];
+			x = m[1];
+			a[0] = 1.5;
+			for a = 0 to 3 {};
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"invalid array length 0 for z",
+		"array length of n must be an int constant",
+		"c has 3 dimensions, arrays have at most 2",
+		"index 4 out of bounds for a (length 4)",
+		"index -1 out of bounds for a (length 4)",
+		"index -1 out of bounds for a (length 4)",
+		"index 3 out of bounds for m (length 3)",
+		"array index must be int (type float)",
+		"array a used without an index",
+		"cannot index x (type int)",
+		"m has 2 dimensions, got 1 indices",
+		"cannot assign float to a[0] (type int)",
+		"array a used without an index",
+	)
+}
+
+func TestCheckBooleans(t *testing.T) {
+	input := `
+		program test: var ok, done: bool; x: int; {
+			ok = true;
+			done = !ok || x > 1 && false;
+			if (ok && !done) {
+				print(ok);
+			};
+			if (x) {};
+			ok = x && true;
+			ok = !x;
+			x = -ok;
+			x = ok;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"non-boolean condition in if statement (type int)",
+		"invalid operation: (x && true) (mismatched types int and bool)",
+		"invalid operation: !x (operator ! not defined on int)",
+		"invalid operation: -ok (operator - not defined on bool)",
+		"cannot assign bool to x (type int)",
+	)
+}
+
+func TestCheckBitwise(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; ok: bool; {
+			x = x % 8 | x & 3 ^ ~x << 2 >> 1;
+			x = f % 2;
+			x = x & f;
+			x = f << x;
+			x = ~f;
+			ok = ok | ok;
+			f = x % 3;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"invalid operation: (f % 2) (operator % not defined on float)",
+		"invalid operation: (x & f) (operator & not defined on float)",
+		"invalid operation: (f << x) (operator << not defined on float)",
+		"invalid operation: ~f (operator ~ not defined on float)",
+		"invalid operation: (ok | ok) (operator | not defined on bool)",
+	)
+}
+
+func TestCheckUnaryMinus(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; ok: bool;
+		func half(v: float): float { return v / 2; }
+		func log() { print(x); }
+		{
+			x = -(x + 1) * -x;
+			f = -half(f) - -f;
+			x = -half(f);
+			ok = -(x > 1);
+			x = -log();
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"cannot assign float to x (type int)",
+		"invalid operation: -(x > 1) (operator - not defined on bool)",
+		"log() (no value) used as value",
+	)
+}
+
+func TestIntConstant(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		ok       bool
+	}{
+		{"4", 4, true},
+		{"-4", -4, true},
+		{"+4", 4, true},
+		{"- -4", 4, true},
+		{"-9223372036854775808", -9223372036854775808, true},
+		{"0x7FFF_FFFF_FFFF_FFFF", 9223372036854775807, true},
+		{"-0x8000000000000000", -9223372036854775808, true},
+		{"0b1010", 10, true},
+		{"1_000", 1000, true},
+		{"010", 10, true},
+		{"~0", -1, true},
+		{"~-4", 3, true},
+		{"-~1", 2, true},
+		{"~x", 0, false},
+		{"9223372036854775808", 0, false},
+		{"-1.5", 0, false},
+		{"x", 0, false},
+	}
+
+	for i, tt := range tests {
+		program, err := goyacc.Parse("program p: var x: int; { x = " + tt.input + "; }")
+		if err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+		v, ok := IntConstant(program.Body.Statements[0].(*ast.Assign).Value)
+		if ok != tt.ok || ok && v != tt.expected {
+			t.Fatalf("tests[%d] - %s wrong. expected=%d %t, got=%d %t", i, tt.input, tt.expected, tt.ok, v, ok)
+		}
+	}
+}
+
+func TestCheckStrings(t *testing.T) {
+	input := `
+		program test: var msg: string; n: int; ok: bool;
+		func greet(name: string): string { return "hi " + name; }
+		{
+			msg = "id=" + n + " t=" + 2.5;
+			msg = greet(msg);
+			n = len(msg) + len("abc");
+			ok = msg == "reset" && msg <> greet("x");
+			msg = n;
+			n = msg;
+			msg = msg + ok;
+			msg = msg * 2;
+			ok = msg < "b";
+			ok = msg == 1;
+			n = len(n);
+			n = len(msg, msg);
+			n = len();
+			n = greet(1);
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"cannot assign int to msg (type string)",
+		"cannot assign string to n (type int)",
+		"invalid operation: (msg + ok) (mismatched types string and bool)",
+		"invalid operation: (msg * 2) (mismatched types string and int)",
+		"invalid operation: (msg < \"b\") (operator < not defined on string)",
+		"invalid operation: (msg == 1) (mismatched types string and int)",
+		"invalid argument n (type int) for len",
+		"too many arguments in call to len (expected 1, got 2)",
+		"not enough arguments in call to len (expected 1, got 0)",
+		"cannot use int as string in argument name of greet",
+		"cannot assign string to n (type int)",
+	)
+}
+
+func TestCheckLenCanBeHidden(t *testing.T) {
+	input := `
+		program test: var n: int;
+		func len(a: int, b: int): int { return a + b; }
+		{
+			n = len(1, 2);
+		}
+	`
+	if _, err := check(t, input); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestCheckNumericLiterals(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; {
+			x = 0xFF & 0b1010 | 1_000;
+			x = -9223372036854775808;
+			x = -0x8000_0000_0000_0000;
+			f = 1.5e-3 + 6.02e23 + 1e-400;
+			x = 9223372036854775808;
+			x = 0x8000000000000000;
+			x = -9223372036854775809;
+			f = 1e400;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"int literal 9223372036854775808 overflows int",
+		"int literal 0x8000000000000000 overflows int",
+		"int literal -9223372036854775809 overflows int",
+		"float literal 1e400 overflows float",
+	)
+}
+
+func TestCheckVarInference(t *testing.T) {
+	input := `
+		program test: var x = 10; var f = 2.5; var ok = x > 1; var msg = "id" + x;
+		var g: float = 2; h: float = x;
+		var s = scale(x);
+		func scale(v: float): float {
+			var k = v * 2;
+			return k;
+		}
+		{
+			x = 20;
+			f = x;
+			g = s;
+		}
+	`
+	info, err := check(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := map[string]Type{"x": Int, "f": Float, "ok": Bool, "msg": String, "g": Float, "h": Float, "s": Float}
+	for name, typ := range expected {
+		symbol, ok := info.Globals.Resolve(name)
+		if !ok || symbol.Type != typ {
+			t.Fatalf("%s wrong. expected=%s, got=%v", name, typ, symbol)
+		}
+	}
+	k, ok := info.Functions["scale"].Scope.Resolve("k")
+	if !ok || k.Type != Float {
+		t.Fatalf("k wrong. expected=float, got=%v", k)
+	}
+}
+
+func TestCheckVarInitializerErrors(t *testing.T) {
+	input := `
+		program test: var x: int = 2.5;
+		var ok: bool = 1;
+		var a, b: int = 1;
+		var arr[2]: int = 1;
+		var self = self + 1;
+		var nothing = log();
+		var y = 1;
+		var y = 2;
+		func log() { print(1); }
+		{
+			nothing = 1;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"cannot use float as int value in declaration of x",
+		"cannot use int as bool value in declaration of ok",
+		"assignment mismatch: 2 variables but 1 value",
+		"cannot initialize array arr",
+		"undeclared identifier self",
+		"log() (no value) used as value",
+		"y redeclared",
+	)
+}
+
+func TestCheckBlockScopes(t *testing.T) {
+	input := `
+		program test: var x = 1;
+		func f(n: int): int {
+			var k = n;
+			if (k > 0) {
+				var n = "inner";
+				print(n);
+			};
+			return k;
+		}
+		{
+			var y = x + 1;
+			if (y > 1) {
+				var x = 2.5;
+				var z: bool;
+				print(x, z);
+			} else {
+				var z = "other";
+				print(z);
+			};
+			x = y;
+		}
+	`
+	info, err := check(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, ok := info.Globals.Resolve("y"); ok {
+		t.Fatalf("block variables should not be globals")
+	}
+
+	expected := []string{
+		"declaration of n shadows an outer variable",
+		"declaration of x shadows an outer variable",
+	}
+	if len(info.Warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got=%d\n%s", len(expected), len(info.Warnings), info.Warnings)
+	}
+	for i, w := range info.Warnings {
+		if w.Severity != diag.Warning || w.Code != diag.Shadowed || !strings.Contains(w.Message, expected[i]) {
+			t.Fatalf("warnings[%d] wrong. expected=%q, got=%q", i, expected[i], w.Message)
+		}
+		if len(w.Notes) != 1 {
+			t.Fatalf("warnings[%d] should point at the shadowed declaration", i)
+		}
+	}
+}
+
+func TestCheckBlockScopeErrors(t *testing.T) {
+	input := `
+		program test: var x = 1;
+		{
+			if (x > 0) {
+				var y = 2;
+			};
+			y = 3;
+			var z = z;
+			var w: int;
+			var w = 1;
+		}
+	`
+	_, err := check(t, input)
+	expectErrors(t, err,
+		"undeclared identifier y",
+		"undeclared identifier z",
+		"w redeclared",
+	)
+}
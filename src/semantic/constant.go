@@ -0,0 +1,76 @@
+package semantic
+
+import (
+	"ciri/src/ast"
+	"ciri/src/token"
+	"strconv"
+	"strings"
+)
+
+// IntValue parses an int literal in any of the forms INT_IDENT accepts, an
+// error wrapping strconv.ErrRange means it does not fit in an int
+func IntValue(literal string) (int64, error) {
+	literal = strings.ReplaceAll(literal, "_", "")
+	digits := strings.TrimLeft(literal, "+-")
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsAny(digits[1:2], "xXbB") {
+		return strconv.ParseInt(literal, 0, 64)
+	}
+	// a leading 0 is not octal
+	return strconv.ParseInt(literal, 10, 64)
+}
+
+// FloatValue parses a float literal
+func FloatValue(literal string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(literal, "_", ""), 64)
+}
+
+// IntConstant evaluates an int literal with optional signs and complements,
+// it is used where a value has to be known at compile time
+func IntConstant(expr ast.Expression) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		if e.Token.Type != token.INT {
+			return 0, false
+		}
+		v, err := IntValue(e.Token.Literal)
+		return v, err == nil
+	case *ast.UnaryExpr:
+		if l, ok := SignedLiteral(e); ok {
+			return IntConstant(l)
+		}
+		v, ok := IntConstant(e.Operand)
+		switch e.Operator {
+		case token.PLUS:
+			return v, ok
+		case token.MINUS:
+			return -v, ok
+		case token.BIT_NOT:
+			return ^v, ok
+		default:
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+}
+
+// SignedLiteral folds a sign written right before a number into the
+// literal, the way INT_IDENT and FLOAT_IDENT spell them, so the most
+// negative int can be written even though its magnitude overflows
+func SignedLiteral(u *ast.UnaryExpr) (*ast.Literal, bool) {
+	l, ok := u.Operand.(*ast.Literal)
+	if !ok || l.Token.Type != token.INT && l.Token.Type != token.FLOAT {
+		return nil, false
+	}
+	if u.Operator == token.PLUS {
+		return l, true
+	}
+	if u.Operator != token.MINUS || l.Token.Literal[0] == '-' {
+		return nil, false
+	}
+
+	tok := l.Token
+	tok.Literal = u.Operator + tok.Literal
+	tok.Offset, tok.Line, tok.Column = u.Token.Offset, u.Token.Line, u.Token.Column
+	return &ast.Literal{Token: tok}, true
+}
@@ -0,0 +1,83 @@
+package semantic
+
+import "ciri/src/token"
+
+// MaxDims is the number of dimensions an array can have
+const MaxDims = 2
+
+type Symbol struct {
+	Name string
+	// Type is the element type of arrays
+	Type Type
+	// Dims holds the length of each dimension of arrays, nil for scalars
+	Dims  []int
+	Token token.Token
+}
+
+// Size is the number of values the symbol holds
+func (s *Symbol) Size() int {
+	size := 1
+	for _, d := range s.Dims {
+		size *= d
+	}
+	return size
+}
+
+// Len is the builtin that counts the bytes of a string, a declared
+// function with the same name hides it
+const Len = "len"
+
+// Function is the signature of a declared function, Scope holds its
+// parameters followed by its local variables
+type Function struct {
+	Name   string
+	Params []*Symbol
+	Result Type
+	Scope  *SymbolTable
+	Token  token.Token
+}
+
+// SymbolTable holds the symbols of one scope, lookups fall back to Outer
+type SymbolTable struct {
+	Outer   *SymbolTable
+	symbols map[string]*Symbol
+	order   []*Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{symbols: make(map[string]*Symbol)}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define adds a symbol to the current scope, if the name is already defined
+// in it the existing symbol is returned with ok set to false
+func (s *SymbolTable) Define(name string, t Type, tok token.Token) (*Symbol, bool) {
+	if existing, ok := s.symbols[name]; ok {
+		return existing, false
+	}
+	symbol := &Symbol{Name: name, Type: t, Token: tok}
+	s.symbols[name] = symbol
+	s.order = append(s.order, symbol)
+	return symbol, true
+}
+
+// Resolve looks name up in the current scope and then in the outer ones
+func (s *SymbolTable) Resolve(name string) (*Symbol, bool) {
+	if symbol, ok := s.symbols[name]; ok {
+		return symbol, true
+	}
+	if s.Outer != nil {
+		return s.Outer.Resolve(name)
+	}
+	return nil, false
+}
+
+// Symbols returns the symbols of the current scope in declaration order
+func (s *SymbolTable) Symbols() []*Symbol {
+	return s.order
+}
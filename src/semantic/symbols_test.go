@@ -0,0 +1,31 @@
+package semantic
+
+import (
+	"ciri/src/token"
+	"testing"
+)
+
+func TestSymbolTableScopes(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("x", Int, token.Token{})
+	global.Define("y", Float, token.Token{})
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("x", Float, token.Token{})
+
+	if s, ok := local.Resolve("x"); !ok || s.Type != Float {
+		t.Fatalf("x should resolve to the local float, got=%v", s)
+	}
+	if s, ok := local.Resolve("y"); !ok || s.Type != Float {
+		t.Fatalf("y should resolve through the outer scope, got=%v", s)
+	}
+	if _, ok := local.Resolve("z"); ok {
+		t.Fatalf("z should not resolve")
+	}
+	if _, ok := global.Define("x", Float, token.Token{}); ok {
+		t.Fatalf("x should already be defined")
+	}
+	if len(global.Symbols()) != 2 {
+		t.Fatalf("expected 2 global symbols, got=%d", len(global.Symbols()))
+	}
+}
@@ -0,0 +1,145 @@
+package semantic
+
+import "ciri/src/token"
+
+type Type int
+
+const (
+	Invalid Type = iota
+	Int
+	Float
+	Bool
+	String
+	// Void is the result of functions that return nothing
+	Void
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case Void:
+		return "void"
+	default:
+		return "invalid"
+	}
+}
+
+// TypeOf maps a declaration type token to its semantic type
+func TypeOf(tokenType token.Type) Type {
+	switch tokenType {
+	case token.INT_TYPE, token.INT:
+		return Int
+	case token.FLOAT_TYPE, token.FLOAT:
+		return Float
+	case token.BOOL_TYPE, token.TRUE, token.FALSE:
+		return Bool
+	case token.STRING_TYPE, token.STRING:
+		return String
+	default:
+		return Invalid
+	}
+}
+
+type operands struct {
+	left  Type
+	right Type
+}
+
+// cube is the semantic cube, operator -> (left, right) -> result type.
+// Combinations that are not listed are type mismatches.
+//
+// ints widen to float implicitly: an int operand of arithmetic or of a
+// comparison with a float is converted first, and Assignable lets an int be
+// stored in a float variable or parameter, returned as a float or used to
+// initialize one (`var f: float = 2`). Nothing narrows, and an inferred
+// declaration (`var x = 2`) takes the type of its value as is.
+var cube = map[string]map[operands]Type{
+	token.PLUS:            concatenation,
+	token.MINUS:           arithmetic,
+	token.MULTIPLY:        arithmetic,
+	token.DIVIDE:          arithmetic,
+	token.MODULO:          integer,
+	token.BIT_AND:         integer,
+	token.BIT_OR:          integer,
+	token.BIT_XOR:         integer,
+	token.SHIFT_LEFT:      integer,
+	token.SHIFT_RIGHT:     integer,
+	token.LESS_THAN:       relational,
+	token.GREATER_THAN:    relational,
+	token.LESS_EQUAL:      relational,
+	token.GREATER_EQUAL:   relational,
+	token.EQUAL:           equality,
+	token.NOT_EQUAL:       equality,
+	token.LESS_THEN_GREAT: equality,
+	token.AND:             logical,
+	token.OR:              logical,
+}
+
+var arithmetic = map[operands]Type{
+	{Int, Int}:     Int,
+	{Int, Float}:   Float,
+	{Float, Int}:   Float,
+	{Float, Float}: Float,
+}
+
+// integer operators are not defined on floats
+var integer = map[operands]Type{
+	{Int, Int}: Int,
+}
+
+// concatenation is arithmetic + joining strings, a number on either side is
+// formatted the way print writes it
+var concatenation = map[operands]Type{
+	{Int, Int}:       Int,
+	{Int, Float}:     Float,
+	{Float, Int}:     Float,
+	{Float, Float}:   Float,
+	{String, String}: String,
+	{String, Int}:    String,
+	{Int, String}:    String,
+	{String, Float}:  String,
+	{Float, String}:  String,
+}
+
+var relational = map[operands]Type{
+	{Int, Int}:     Bool,
+	{Int, Float}:   Bool,
+	{Float, Int}:   Bool,
+	{Float, Float}: Bool,
+}
+
+// equality also compares bools and strings
+var equality = map[operands]Type{
+	{Int, Int}:       Bool,
+	{Int, Float}:     Bool,
+	{Float, Int}:     Bool,
+	{Float, Float}:   Bool,
+	{Bool, Bool}:     Bool,
+	{String, String}: Bool,
+}
+
+var logical = map[operands]Type{
+	{Bool, Bool}: Bool,
+}
+
+// Result looks up the type produced by applying operator to left and right,
+// Invalid is returned when the combination is not allowed
+func Result(operator string, left, right Type) Type {
+	if results, ok := cube[operator]; ok {
+		return results[operands{left, right}]
+	}
+	return Invalid
+}
+
+// Assignable reports whether a value of type value can be stored in a
+// variable of type target. ints are widened to floats.
+func Assignable(target, value Type) bool {
+	return target == value || target == Float && value == Int
+}
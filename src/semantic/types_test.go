@@ -0,0 +1,69 @@
+package semantic
+
+import (
+	"ciri/src/token"
+	"testing"
+)
+
+func TestSemanticCube(t *testing.T) {
+	tests := []struct {
+		operator string
+		left     Type
+		right    Type
+		expected Type
+	}{
+		{token.PLUS, Int, Int, Int},
+		{token.PLUS, Int, Float, Float},
+		{token.MINUS, Float, Int, Float},
+		{token.MULTIPLY, Float, Float, Float},
+		{token.DIVIDE, Int, Int, Int},
+		{token.LESS_THAN, Int, Float, Bool},
+		{token.GREATER_THAN, Float, Float, Bool},
+		{token.LESS_THEN_GREAT, Int, Int, Bool},
+		{token.LESS_EQUAL, Int, Float, Bool},
+		{token.GREATER_EQUAL, Float, Int, Bool},
+		{token.EQUAL, Bool, Bool, Bool},
+		{token.NOT_EQUAL, Float, Float, Bool},
+		{token.LESS_THEN_GREAT, Bool, Bool, Bool},
+		{token.LESS_EQUAL, Bool, Bool, Invalid},
+		{token.EQUAL, Bool, Int, Invalid},
+		{token.AND, Bool, Bool, Bool},
+		{token.OR, Bool, Bool, Bool},
+		{token.OR, Bool, Int, Invalid},
+		{token.PLUS, Bool, Int, Invalid},
+		{token.LESS_THAN, String, String, Invalid},
+		{token.MODULO, Int, Int, Int},
+		{token.MODULO, Float, Int, Invalid},
+		{token.BIT_AND, Int, Int, Int},
+		{token.BIT_OR, Int, Float, Invalid},
+		{token.BIT_XOR, Bool, Bool, Invalid},
+		{token.SHIFT_LEFT, Int, Int, Int},
+		{token.SHIFT_RIGHT, Float, Float, Invalid},
+		{token.PLUS, String, String, String},
+		{token.PLUS, String, Int, String},
+		{token.PLUS, Float, String, String},
+		{token.PLUS, String, Bool, Invalid},
+		{token.MINUS, String, String, Invalid},
+		{token.EQUAL, String, String, Bool},
+		{token.NOT_EQUAL, String, String, Bool},
+		{token.EQUAL, String, Int, Invalid},
+		{"?", Int, Int, Invalid},
+	}
+
+	for i, tt := range tests {
+		result := Result(tt.operator, tt.left, tt.right)
+		if result != tt.expected {
+			t.Fatalf("tests[%d] - %s %s %s wrong. expected=%s, got=%s",
+				i, tt.left, tt.operator, tt.right, tt.expected, result)
+		}
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	if !Assignable(Float, Int) {
+		t.Fatalf("int should widen to float")
+	}
+	if Assignable(Int, Float) {
+		t.Fatalf("float should not narrow to int")
+	}
+}
@@ -7,11 +7,18 @@ import (
 type Type string
 
 type Token struct {
-	Type            Type
-	LineNumber      uint32
-	CharacterNumber uint32
-	Literal         string
-	IsKeyword       bool
+	Type    Type
+	Literal string
+	// Value is what a string literal stands for once its quotes are removed
+	// and its escape sequences decoded
+	Value string
+	// Offset and EndOffset are the byte range of the token in the source
+	Offset    int
+	EndOffset int
+	// Line and Column are 1-based, Column counts UTF-8 characters
+	Line      uint32
+	Column    uint32
+	IsKeyword bool
 }
 
 type Keyword struct {
@@ -20,20 +27,31 @@ type Keyword struct {
 }
 
 var IDENT = Keyword{Regex: "[a-zA-Z](a-zA-Z]|[0-9])*", Type: ID}
-var FLOAT_IDENT = Keyword{Regex: "[+-]?([0-9]*[.])[0-9]+", Type: FLOAT}
-var INT_IDENT = Keyword{Regex: "[-+]?[0-9]+", Type: INT}
+
+// Numbers may separate digits with single underscores, ints can be written
+// in hex with 0x and in binary with 0b
+var FLOAT_IDENT = Keyword{Regex: `^[-+]?[0-9](_?[0-9])*(\.[0-9](_?[0-9])*([eE][-+]?[0-9](_?[0-9])*)?|[eE][-+]?[0-9](_?[0-9])*)$`, Type: FLOAT}
+var INT_IDENT = Keyword{Regex: `^[-+]?(0[xX](_?[0-9a-fA-F])+|0[bB](_?[01])+|[0-9](_?[0-9])*)$`, Type: INT}
 
 var simpleKeywords = map[string]Keyword{
 	"var":     Keyword{Type: VAR},
 	"int":     Keyword{Type: INT_TYPE},
 	"float":   Keyword{Type: FLOAT_TYPE},
+	"bool":    Keyword{Type: BOOL_TYPE},
+	"string":  Keyword{Type: STRING_TYPE},
 	"<>":      Keyword{Type: LESS_THEN_GREAT},
 	"program": Keyword{Type: PROGRAM},
 	"true":    Keyword{Type: TRUE},
 	"false":   Keyword{Type: FALSE},
 	"if":      Keyword{Type: IF},
 	"else":    Keyword{Type: ELSE},
+	"while":   Keyword{Type: WHILE},
+	"for":     Keyword{Type: FOR},
+	"to":      Keyword{Type: TO},
+	"step":    Keyword{Type: STEP},
 	"print":   Keyword{Type: PRINT},
+	"func":    Keyword{Type: FUNC},
+	"return":  Keyword{Type: RETURN},
 }
 
 const (
@@ -41,22 +59,49 @@ const (
 	FALSE = "FALSE"
 	IF    = "IF"
 	ELSE  = "ELSE"
+	WHILE = "WHILE"
+	FOR   = "FOR"
+	TO    = "TO"
+	STEP  = "STEP"
+
+	FUNC   = "FUNC"
+	RETURN = "RETURN"
 
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	// COMMENT tokens are trivia, the lexer keeps them aside
+	COMMENT = "COMMENT"
 
 	PLUS     = "+"
 	MINUS    = "-"
 	MULTIPLY = "*"
 	DIVIDE   = "/"
+	MODULO   = "%"
+
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	BIT_NOT     = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
+	AND = "&&"
+	OR  = "||"
+	NOT = "!"
 
 	GREATER_THAN       = ">"
 	LESS_THAN          = "<"
 	LESS_THEN_GREAT    = "<>"
+	LESS_EQUAL         = "<="
+	GREATER_EQUAL      = ">="
+	EQUAL              = "=="
+	NOT_EQUAL          = "!="
 	OPEN_PARENTHESIS   = "("
 	CLOSED_PARENTHESIS = ")"
 	OPEN_BRACE         = "{"
 	CLOSED_BRACE       = "}"
+	OPEN_BRACKET       = "["
+	CLOSED_BRACKET     = "]"
 
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -69,10 +114,12 @@ const (
 	PRINT   = "print"
 	STRING  = "STRING"
 
-	INT_TYPE   = "INT_TYPE"
-	FLOAT_TYPE = "FLOAT_TYPE"
-	INT        = "INT"
-	FLOAT      = "FLOAT"
+	INT_TYPE    = "INT_TYPE"
+	FLOAT_TYPE  = "FLOAT_TYPE"
+	BOOL_TYPE   = "BOOL_TYPE"
+	STRING_TYPE = "STRING_TYPE"
+	INT         = "INT"
+	FLOAT       = "FLOAT"
 )
 
 func LookupIdentifier(keyword Keyword, potentialKeyword string) Type {
@@ -21,10 +21,34 @@ func TestLookUpSimpleKeywords(t *testing.T) {
 			expectedType:    ELSE,
 			expectedLiteral: "else",
 		},
+		{
+			expectedType:    WHILE,
+			expectedLiteral: "while",
+		},
+		{
+			expectedType:    FOR,
+			expectedLiteral: "for",
+		},
+		{
+			expectedType:    TO,
+			expectedLiteral: "to",
+		},
+		{
+			expectedType:    STEP,
+			expectedLiteral: "step",
+		},
 		{
 			expectedType:    PRINT,
 			expectedLiteral: "print",
 		},
+		{
+			expectedType:    FUNC,
+			expectedLiteral: "func",
+		},
+		{
+			expectedType:    RETURN,
+			expectedLiteral: "return",
+		},
 		{
 			expectedType:    TRUE,
 			expectedLiteral: "true",
@@ -42,6 +66,14 @@ func TestLookUpSimpleKeywords(t *testing.T) {
 			expectedType:    FLOAT_TYPE,
 			expectedLiteral: "float",
 		},
+		{
+			expectedType:    BOOL_TYPE,
+			expectedLiteral: "bool",
+		},
+		{
+			expectedType:    STRING_TYPE,
+			expectedLiteral: "string",
+		},
 		{
 			expectedType:    VAR,
 			expectedLiteral: "var",
@@ -66,3 +98,44 @@ func TestLookUpSimpleKeywords(t *testing.T) {
 		}
 	}
 }
+
+func TestLookupNumbers(t *testing.T) {
+	tests := []struct {
+		literal  string
+		expected Type
+	}{
+		{"42", INT},
+		{"007", INT},
+		{"1_000_000", INT},
+		{"0x1F", INT},
+		{"0Xdead_BEEF", INT},
+		{"0b1010", INT},
+		{"0B_1111_0000", INT},
+		{"-12", INT},
+		{"1.5", FLOAT},
+		{"1_000.000_1", FLOAT},
+		{"1.5e-3", FLOAT},
+		{"2E10", FLOAT},
+		{"6.02e+23", FLOAT},
+		{"1.2.3", ILLEGAL},
+		{"1__0", ILLEGAL},
+		{"1_", ILLEGAL},
+		{"0x", ILLEGAL},
+		{"0b102", ILLEGAL},
+		{"0x1G", ILLEGAL},
+		{"1e", ILLEGAL},
+		{"1.", ILLEGAL},
+		{"1.5e3.2", ILLEGAL},
+		{"12abc", ILLEGAL},
+	}
+
+	for i, tt := range tests {
+		tokenType := LookupIdentifier(INT_IDENT, tt.literal)
+		if tokenType == ILLEGAL {
+			tokenType = LookupIdentifier(FLOAT_IDENT, tt.literal)
+		}
+		if tokenType != tt.expected {
+			t.Fatalf("tests[%d] - %s wrong. expected=%q, got=%q", i, tt.literal, tt.expected, tokenType)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"ciri/src/ir"
+	"ciri/src/semantic"
+)
+
+// memory holds one typed slice per type so values are never boxed
+type memory struct {
+	ints    []int64
+	floats  []float64
+	bools   []bool
+	strings []string
+}
+
+func newMemory(size ir.Size) memory {
+	return memory{
+		ints:    make([]int64, size.Ints),
+		floats:  make([]float64, size.Floats),
+		bools:   make([]bool, size.Bools),
+		strings: make([]string, size.Strings),
+	}
+}
+
+// len returns the number of slots for type t
+func (m *memory) len(t semantic.Type) int {
+	switch t {
+	case semantic.Int:
+		return len(m.ints)
+	case semantic.Float:
+		return len(m.floats)
+	case semantic.Bool:
+		return len(m.bools)
+	default:
+		return len(m.strings)
+	}
+}
+
+// clear zeroes n slots starting at a
+func (m *memory) clear(a ir.Addr, n int) {
+	i := a.Index()
+	switch a.Type() {
+	case semantic.Int:
+		for j := range m.ints[i : i+n] {
+			m.ints[i+j] = 0
+		}
+	case semantic.Float:
+		for j := range m.floats[i : i+n] {
+			m.floats[i+j] = 0
+		}
+	case semantic.Bool:
+		for j := range m.bools[i : i+n] {
+			m.bools[i+j] = false
+		}
+	default:
+		for j := range m.strings[i : i+n] {
+			m.strings[i+j] = ""
+		}
+	}
+}
+
+func constantMemory(c ir.Constants) memory {
+	return memory{
+		ints:    c.Ints,
+		floats:  c.Floats,
+		bools:   c.Bools,
+		strings: c.Strings,
+	}
+}
+
+// frame is the memory of one function activation
+type frame struct {
+	function *ir.Function
+	locals   memory
+	temps    memory
+	// ret is the quadruple the caller continues at
+	ret int
+}
@@ -0,0 +1,403 @@
+package vm
+
+import (
+	"ciri/src/ir"
+	"ciri/src/semantic"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// checkInterval is how many quadruples run between context checks
+const checkInterval = 1024
+
+// maxCallDepth bounds recursion so runaway programs fail instead of
+// exhausting memory
+const maxCallDepth = 10000
+
+type RuntimeError struct {
+	Line uint32
+	Msg  string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at line %d: %s", e.Line, e.Msg)
+}
+
+type VM struct {
+	program *ir.Program
+	out     io.Writer
+
+	globals   memory
+	constants memory
+
+	// frame is the running activation, calls holds the ones waiting for it
+	// to return and pending the one being prepared by ERA and PARAM
+	frame   *frame
+	calls   []*frame
+	pending *frame
+
+	ip int
+	// line tells if something was printed since the last newline
+	line bool
+	buf  []byte
+}
+
+func New(program *ir.Program, out io.Writer) *VM {
+	return &VM{
+		program:   program,
+		out:       out,
+		globals:   newMemory(program.GlobalSize),
+		constants: constantMemory(program.Constants),
+		frame:     &frame{temps: newMemory(program.TempSize)},
+		buf:       make([]byte, 0, 64),
+	}
+}
+
+// Run executes program writing everything it prints to out
+func Run(ctx context.Context, program *ir.Program, out io.Writer) error {
+	return New(program, out).Run(ctx)
+}
+
+// Run executes quadruples until END is reached, the context is done or a
+// runtime error occurs
+func (vm *VM) Run(ctx context.Context) error {
+	quads := vm.program.Quads
+
+	for steps := 0; vm.ip < len(quads); steps++ {
+		if steps%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		q := &quads[vm.ip]
+		vm.ip++
+
+		switch q.Op {
+		case ir.ADD, ir.SUB, ir.MUL, ir.DIV, ir.MOD, ir.BAND, ir.BOR, ir.BXOR, ir.SHL, ir.SHR:
+			if err := vm.arithmetic(q); err != nil {
+				return err
+			}
+		case ir.NEG:
+			if q.Result.Type() == semantic.Int {
+				vm.setInt(q.Result, -vm.int(q.Left))
+			} else {
+				vm.setFloat(q.Result, -vm.float(q.Left))
+			}
+		case ir.LESS_THAN, ir.GREATER_THAN, ir.NOT_EQUAL, ir.LESS_EQUAL, ir.GREATER_EQUAL, ir.EQUAL:
+			vm.setBool(q.Result, vm.compare(q))
+		case ir.ASSIGN:
+			vm.assign(q.Left, q.Result)
+		case ir.PRINT:
+			if err := vm.print(q.Left); err != nil {
+				return err
+			}
+		case ir.PRINTLN:
+			vm.line = false
+			if _, err := io.WriteString(vm.out, "\n"); err != nil {
+				return err
+			}
+		case ir.GOTO:
+			vm.ip = int(q.Result)
+		case ir.GOTOF:
+			if !vm.bool(q.Left) {
+				vm.ip = int(q.Result)
+			}
+		case ir.GOTOT:
+			if vm.bool(q.Left) {
+				vm.ip = int(q.Result)
+			}
+		case ir.NOT:
+			vm.setBool(q.Result, !vm.bool(q.Left))
+		case ir.BNOT:
+			vm.setInt(q.Result, ^vm.int(q.Left))
+		case ir.CONCAT:
+			b := vm.format(vm.buf[:0], q.Left)
+			b = vm.format(b, q.Right)
+			vm.buf = b
+			vm.setString(q.Result, string(b))
+		case ir.LEN:
+			vm.setInt(q.Result, int64(len(vm.string(q.Left))))
+		case ir.CHECKSTEP:
+			if vm.int(q.Left) == 0 {
+				return vm.errorf(q, "for loop step cannot be zero")
+			}
+		case ir.CLEAR:
+			vm.memory(q.Left).clear(q.Left, int(q.Result))
+		case ir.ERA:
+			f := &vm.program.Functions[q.Result]
+			vm.pending = &frame{function: f, locals: newMemory(f.LocalSize), temps: newMemory(f.TempSize)}
+		case ir.PARAM:
+			vm.param(q.Left, vm.pending.function.Params[q.Result])
+		case ir.GOSUB:
+			if len(vm.calls) >= maxCallDepth {
+				return vm.errorf(q, "stack overflow calling %s", vm.program.Functions[q.Result].Name)
+			}
+			vm.pending.ret = vm.ip
+			vm.calls = append(vm.calls, vm.frame)
+			vm.frame, vm.pending = vm.pending, nil
+			vm.ip = vm.frame.function.Start
+		case ir.RETURN:
+			vm.assign(q.Left, q.Result)
+			vm.ret()
+		case ir.ENDFUNC:
+			vm.ret()
+		case ir.VERIFY:
+			if index := vm.int(q.Left); index < 0 || index >= int64(q.Result) {
+				return vm.errorf(q, "index out of range [%d] with length %d", index, q.Result)
+			}
+		case ir.LOAD:
+			element, err := vm.element(q, q.Left, q.Right)
+			if err != nil {
+				return err
+			}
+			vm.assign(element, q.Result)
+		case ir.STORE:
+			element, err := vm.element(q, q.Result, q.Right)
+			if err != nil {
+				return err
+			}
+			vm.assign(q.Left, element)
+		case ir.END:
+			return nil
+		default:
+			return vm.errorf(q, "unknown operation %s", q.Op)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) errorf(q *ir.Quad, format string, args ...interface{}) error {
+	return &RuntimeError{Line: q.Line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Operations
+
+// param copies from in the caller frame into the parameter to of the
+// pending frame
+func (vm *VM) param(from, to ir.Addr) {
+	m := &vm.pending.locals
+	switch to.Type() {
+	case semantic.Int:
+		m.ints[to.Index()] = vm.int(from)
+	case semantic.Float:
+		m.floats[to.Index()] = vm.float(from)
+	case semantic.Bool:
+		m.bools[to.Index()] = vm.bool(from)
+	case semantic.String:
+		m.strings[to.Index()] = vm.string(from)
+	}
+}
+
+// element returns the address offset slots after base, it has to stay in
+// the memory of base since the offset is only known at runtime
+func (vm *VM) element(q *ir.Quad, base, offset ir.Addr) (ir.Addr, error) {
+	n := vm.int(offset)
+	if n < 0 || int64(base.Index())+n >= int64(vm.memory(base).len(base.Type())) {
+		return 0, vm.errorf(q, "memory access out of range")
+	}
+	return base + ir.Addr(n), nil
+}
+
+// ret leaves the running function
+func (vm *VM) ret() {
+	vm.ip = vm.frame.ret
+	vm.frame = vm.calls[len(vm.calls)-1]
+	vm.calls = vm.calls[:len(vm.calls)-1]
+}
+
+func (vm *VM) arithmetic(q *ir.Quad) error {
+	if q.Result.Type() == semantic.Int {
+		left, right := vm.int(q.Left), vm.int(q.Right)
+		var v int64
+		switch q.Op {
+		case ir.ADD:
+			v = left + right
+		case ir.SUB:
+			v = left - right
+		case ir.MUL:
+			v = left * right
+		case ir.DIV:
+			if right == 0 {
+				return vm.errorf(q, "integer division by zero")
+			}
+			v = left / right
+		case ir.MOD:
+			if right == 0 {
+				return vm.errorf(q, "integer modulo by zero")
+			}
+			v = left % right
+		case ir.BAND:
+			v = left & right
+		case ir.BOR:
+			v = left | right
+		case ir.BXOR:
+			v = left ^ right
+		case ir.SHL, ir.SHR:
+			// counts past the width shift every bit out, like in Go
+			if right < 0 {
+				return vm.errorf(q, "negative shift amount %d", right)
+			}
+			if q.Op == ir.SHL {
+				v = left << uint64(right)
+			} else {
+				v = left >> uint64(right)
+			}
+		}
+		vm.setInt(q.Result, v)
+		return nil
+	}
+
+	left, right := vm.float(q.Left), vm.float(q.Right)
+	var v float64
+	switch q.Op {
+	case ir.ADD:
+		v = left + right
+	case ir.SUB:
+		v = left - right
+	case ir.MUL:
+		v = left * right
+	case ir.DIV:
+		v = left / right
+	}
+	vm.setFloat(q.Result, v)
+	return nil
+}
+
+func (vm *VM) compare(q *ir.Quad) bool {
+	switch q.Left.Type() {
+	case semantic.Bool:
+		return (vm.bool(q.Left) == vm.bool(q.Right)) == (q.Op == ir.EQUAL)
+	case semantic.String:
+		return (vm.string(q.Left) == vm.string(q.Right)) == (q.Op == ir.EQUAL)
+	}
+
+	if q.Left.Type() == semantic.Int && q.Right.Type() == semantic.Int {
+		left, right := vm.int(q.Left), vm.int(q.Right)
+		switch q.Op {
+		case ir.LESS_THAN:
+			return left < right
+		case ir.GREATER_THAN:
+			return left > right
+		case ir.LESS_EQUAL:
+			return left <= right
+		case ir.GREATER_EQUAL:
+			return left >= right
+		case ir.EQUAL:
+			return left == right
+		default:
+			return left != right
+		}
+	}
+
+	left, right := vm.float(q.Left), vm.float(q.Right)
+	switch q.Op {
+	case ir.LESS_THAN:
+		return left < right
+	case ir.GREATER_THAN:
+		return left > right
+	case ir.LESS_EQUAL:
+		return left <= right
+	case ir.GREATER_EQUAL:
+		return left >= right
+	case ir.EQUAL:
+		return left == right
+	default:
+		return left != right
+	}
+}
+
+func (vm *VM) assign(from, to ir.Addr) {
+	switch to.Type() {
+	case semantic.Int:
+		vm.setInt(to, vm.int(from))
+	case semantic.Float:
+		vm.setFloat(to, vm.float(from))
+	case semantic.Bool:
+		vm.setBool(to, vm.bool(from))
+	case semantic.String:
+		vm.setString(to, vm.string(from))
+	}
+}
+
+func (vm *VM) print(a ir.Addr) error {
+	b := vm.buf[:0]
+	if vm.line {
+		b = append(b, ' ')
+	}
+
+	b = vm.format(b, a)
+
+	vm.buf = b
+	vm.line = true
+	_, err := vm.out.Write(b)
+	return err
+}
+
+// format appends the value at a to b
+func (vm *VM) format(b []byte, a ir.Addr) []byte {
+	switch a.Type() {
+	case semantic.Int:
+		return strconv.AppendInt(b, vm.int(a), 10)
+	case semantic.Float:
+		return strconv.AppendFloat(b, vm.float(a), 'g', -1, 64)
+	case semantic.Bool:
+		return strconv.AppendBool(b, vm.bool(a))
+	default:
+		return append(b, vm.string(a)...)
+	}
+}
+
+// Memory access
+
+func (vm *VM) memory(a ir.Addr) *memory {
+	switch a.Segment() {
+	case ir.Global:
+		return &vm.globals
+	case ir.Temp:
+		return &vm.frame.temps
+	case ir.Local:
+		return &vm.frame.locals
+	default:
+		return &vm.constants
+	}
+}
+
+func (vm *VM) int(a ir.Addr) int64 {
+	return vm.memory(a).ints[a.Index()]
+}
+
+// float reads a as a float, ints are widened
+func (vm *VM) float(a ir.Addr) float64 {
+	if a.Type() == semantic.Int {
+		return float64(vm.int(a))
+	}
+	return vm.memory(a).floats[a.Index()]
+}
+
+func (vm *VM) bool(a ir.Addr) bool {
+	return vm.memory(a).bools[a.Index()]
+}
+
+func (vm *VM) string(a ir.Addr) string {
+	return vm.memory(a).strings[a.Index()]
+}
+
+func (vm *VM) setInt(a ir.Addr, v int64) {
+	vm.memory(a).ints[a.Index()] = v
+}
+
+func (vm *VM) setFloat(a ir.Addr, v float64) {
+	vm.memory(a).floats[a.Index()] = v
+}
+
+func (vm *VM) setBool(a ir.Addr, v bool) {
+	vm.memory(a).bools[a.Index()] = v
+}
+
+func (vm *VM) setString(a ir.Addr, v string) {
+	vm.memory(a).strings[a.Index()] = v
+}
@@ -0,0 +1,559 @@
+package vm
+
+import (
+	"bytes"
+	"ciri/src/goyacc"
+	"ciri/src/ir"
+	"ciri/src/semantic"
+	"context"
+	"testing"
+)
+
+func compile(t *testing.T, input string) *ir.Program {
+	program, err := goyacc.Parse(input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := semantic.Check(program)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	p, err := ir.Generate(program, info)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return p
+}
+
+func run(t *testing.T, input string) (string, error) {
+	var out bytes.Buffer
+	err := Run(context.Background(), compile(t, input), &out)
+	return out.String(), err
+}
+
+func expectOutput(t *testing.T, input string, expected string) {
+	output, err := run(t, input)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if output != expected {
+		t.Fatalf("output wrong.\nexpected=%q\ngot=     %q", expected, output)
+	}
+}
+
+func TestRunArithmetic(t *testing.T) {
+	input := `
+		program test: var x, y: int; f: float; {
+			x = 10;
+			y = x + 2 * 10;
+			f = -x / 4.0;
+			print(x, y, f);
+			print(7 / 2, 7.0 / 2, x - y);
+			f = y;
+			print(f);
+		}
+	`
+	expectOutput(t, input, "10 30 -2.5\n3 3.5 -20\n30\n")
+}
+
+func TestRunComparisons(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; {
+			x = 3;
+			f = 2.5;
+			print(x > f, x < f, x < 4, 1 > 1);
+		}
+	`
+	expectOutput(t, input, "true false true false\n")
+}
+
+func TestRunConditionals(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			x = 20 + 50;
+
+			if (x < 10) {
+				print("small");
+			} else {
+				print("big");
+				if (x > 60) {
+					print("huge");
+				};
+			};
+
+			print("hello", x, x > 10);
+		}
+	`
+	expectOutput(t, input, "big\nhuge\nhello 70 true\n")
+}
+
+func TestRunDivisionByZero(t *testing.T) {
+	input := `
+		program test: var x: int; {
+			x = 0;
+			print(10 / x);
+		}
+	`
+	_, err := run(t, input)
+	if err == nil {
+		t.Fatalf("expected a runtime error")
+	}
+
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected *RuntimeError, got=%T", err)
+	}
+	if runtimeErr.Line != 4 {
+		t.Fatalf("line wrong. expected=%d, got=%d", 4, runtimeErr.Line)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	err := Run(ctx, compile(t, `program test: { print(1); }`), &out)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got=%v", err)
+	}
+}
+
+func TestRunWhile(t *testing.T) {
+	input := `
+		program test: var i, total: int; {
+			i = 0;
+			total = 0;
+			while (i < 5) {
+				i = i + 1;
+				if (i > 3) {
+					print("big", i);
+				};
+				total = total + i;
+			};
+			print(total);
+			while (i < 0) {
+				print("never");
+			};
+		}
+	`
+	expectOutput(t, input, "big 4\nbig 5\n15\n")
+}
+
+func TestRunFor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`program test: var i: int; { for i = 0 to 6 step 2 { print(i); }; print(i); }`, "0\n2\n4\n6\n8\n"},
+		{`program test: var i: int; { for i = 3 to 1 step -1 { print(i); }; }`, "3\n2\n1\n"},
+		{`program test: var i: int; { for i = 1 to 3 { print(i); }; }`, "1\n2\n3\n"},
+		{`program test: var i: int; { for i = 5 to 1 { print(i); }; print("done"); }`, "done\n"},
+		{`program test: var i, s: int; { s = -2; for i = 4 to 0 step s { print(i); }; }`, "4\n2\n0\n"},
+		// the end and step are evaluated once
+		{`program test: var i, n, s: int; { n = 2; s = 1; for i = 0 to n step s { n = 10; s = 5; print(i); }; }`, "0\n1\n2\n"},
+		// an end at the edge of the int range stops the loop
+		{`program test: var i: int; { for i = 9223372036854775806 to 9223372036854775807 { print(i); }; }`, "9223372036854775806\n9223372036854775807\n"},
+		{`program test: var i, s: int; { s = -1; for i = -9223372036854775807 to -9223372036854775808 step s { print(i); }; }`, "-9223372036854775807\n-9223372036854775808\n"},
+	}
+
+	for _, tt := range tests {
+		expectOutput(t, tt.input, tt.expected)
+	}
+}
+
+func TestRunInfiniteLoopCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := compile(t, `program test: var x: int; { while (0 < 1) { x = x + 1; }; }`)
+
+	var out bytes.Buffer
+	machine := New(p, &out)
+	done := make(chan error)
+	go func() { done <- machine.Run(ctx) }()
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got=%v", err)
+	}
+}
+
+func TestRunFunctions(t *testing.T) {
+	input := `
+		program test: var total: int;
+		func fib(n: int): int {
+			if (n < 2) {
+				return n;
+			};
+			return fib(n - 1) + fib(n - 2);
+		}
+		func half(v: float): float {
+			return v / 2;
+		}
+		func count(n: int) {
+			var i: int;
+			for i = 1 to n {
+				total = total + i;
+			};
+			print("counted", n);
+		}
+		{
+			print(fib(10), fib(fib(5)));
+			print(half(3), half(fib(4)) * 2);
+			count(3);
+			count(4);
+			print(total);
+		}
+	`
+	expectOutput(t, input, "55 5\n1.5 3\ncounted 3\ncounted 4\n16\n")
+}
+
+func TestRunRecursionKeepsFrames(t *testing.T) {
+	input := `
+		program test:
+		func down(n: int) {
+			var local: int;
+			local = n * 10;
+			if (n > 0) {
+				down(n - 1);
+			};
+			print(n, local);
+		}
+		{
+			down(2);
+		}
+	`
+	expectOutput(t, input, "0 0\n1 10\n2 20\n")
+}
+
+func TestRunStackOverflow(t *testing.T) {
+	input := `
+		program test:
+		func forever(n: int): int {
+			return forever(n + 1);
+		}
+		{
+			print(forever(0));
+		}
+	`
+	_, err := run(t, input)
+	rerr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected *RuntimeError, got=%T (%v)", err, err)
+	}
+	if rerr.Line != 4 || rerr.Msg != "stack overflow calling forever" {
+		t.Fatalf("error wrong, got=%v", rerr)
+	}
+}
+
+func TestRunArrays(t *testing.T) {
+	input := `
+		program test: var readings[5]: float; grid[3][3], i, j: int;
+		func sum(n: int): float {
+			var k: int; total: float; scratch[2]: float;
+			for k = 0 to n - 1 {
+				total = total + readings[k];
+			};
+			scratch[1] = total;
+			return scratch[1];
+		}
+		{
+			for i = 0 to 4 {
+				readings[i] = i * 1.5;
+			};
+			for i = 0 to 2 {
+				for j = 0 to 2 {
+					grid[i][j] = i * 10 + j;
+				};
+			};
+			readings[0] = grid[2][1];
+			print(readings[0], readings[4], sum(5));
+			print(grid[1][2], grid[i - 1][j - 3], grid[0][0]);
+		}
+	`
+	expectOutput(t, input, "21 6 36\n12 20 0\n")
+}
+
+func TestRunIndexOutOfRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`program test: var a[4], i: int; {
+			i = 4;
+			a[i] = 1;
+		}`, "index out of range [4] with length 4"},
+		{`program test: var g[2][3], i: int; {
+			i = -1;
+			print(g[1][i]);
+		}`, "index out of range [-1] with length 3"},
+	}
+
+	for i, tt := range tests {
+		_, err := run(t, tt.input)
+		runtimeErr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Fatalf("tests[%d] - expected *RuntimeError, got=%T (%v)", i, err, err)
+		}
+		if runtimeErr.Line != 3 || runtimeErr.Msg != tt.expected {
+			t.Fatalf("tests[%d] - error wrong. expected=%q at line 3, got=%v", i, tt.expected, runtimeErr)
+		}
+	}
+}
+
+func TestRunLoadOutsideMemory(t *testing.T) {
+	// programs loaded from bytecode skip the checker, so the VM bounds
+	// every access on its own
+	g0 := ir.NewAddr(ir.Global, semantic.Int, 0)
+	p := &ir.Program{
+		GlobalSize: ir.Size{Ints: 2},
+		Constants:  ir.Constants{Ints: []int64{2}},
+		Quads: []ir.Quad{
+			{Op: ir.LOAD, Left: g0, Right: ir.NewAddr(ir.Const, semantic.Int, 0), Result: g0, Line: 1},
+			{Op: ir.END},
+		},
+	}
+
+	var out bytes.Buffer
+	err := Run(context.Background(), p, &out)
+	if runtimeErr, ok := err.(*RuntimeError); !ok || runtimeErr.Msg != "memory access out of range" {
+		t.Fatalf("expected an out of range error, got=%v", err)
+	}
+}
+
+func TestRunBooleans(t *testing.T) {
+	input := `
+		program test: var ok, done: bool; x: int; {
+			ok = true;
+			print(ok, !ok, ok && done, ok || done, !(ok && !done));
+			x = 0;
+			if (x > 0 && 10 / x > 1) {
+				print("unreachable");
+			};
+			if (x < 1 || 10 / x > 1) {
+				print("short-circuit");
+			};
+			done = x < 1;
+			while (!done) {};
+			print(done);
+		}
+	`
+	expectOutput(t, input, "true false false true false\nshort-circuit\ntrue\n")
+}
+
+func TestRunEquality(t *testing.T) {
+	input := `
+		program test: var x: int; f: float; ok: bool; {
+			x = 2;
+			f = 2.0;
+			print(x == 2, x != 2, x <> 3, x <= 2, x >= 3);
+			print(x == f, f <= 1.5, f >= x);
+			ok = x == 2;
+			print(ok == true, ok != ok, ok <> false);
+		}
+	`
+	expectOutput(t, input, "true false true true false\ntrue false true\ntrue false true\n")
+}
+
+func TestRunBitwise(t *testing.T) {
+	input := `
+		program test: var x, n: int; {
+			x = 202;
+			print(x % 16, -7 % 3, 7 / 2, -7 / 2);
+			print(x & 15, x | 1, x ^ 255, ~x);
+			print(1 << 4, x >> 4, -16 >> 2, (x >> 4 & 15) << 8 | x & 15);
+			n = 64;
+			print(1 << n, -1 >> n, 5 >> 63);
+		}
+	`
+	expectOutput(t, input, "10 -1 3 -3\n10 203 53 -203\n16 12 -4 3082\n0 -1 0\n")
+}
+
+func TestRunComplementConstants(t *testing.T) {
+	input := `
+		program test: var b[~-3]: int; i: int; {
+			for i = 3 to 0 step ~0 { print(i); };
+			for i = 3 to 0 step ~1 { print(i); };
+			for i = 0 to 1 step ~-2 { b[i] = i; print(b[i]); };
+		}
+	`
+	expectOutput(t, input, "3\n2\n1\n0\n3\n1\n0\n1\n")
+}
+
+func TestRunIntegerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`program test: var x: int; {
+			x = 0;
+			print(10 % x);
+		}`, "integer modulo by zero"},
+		{`program test: var x: int; {
+			x = -1;
+			print(10 << x);
+		}`, "negative shift amount -1"},
+		{`program test: var x: int; {
+			x = -3;
+			print(10 >> x);
+		}`, "negative shift amount -3"},
+		{`program test: var i, s: int; {
+			s = 0;
+			for i = 0 to 3 step s { print(i); };
+		}`, "for loop step cannot be zero"},
+	}
+
+	for i, tt := range tests {
+		_, err := run(t, tt.input)
+		runtimeErr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Fatalf("tests[%d] - expected *RuntimeError, got=%T (%v)", i, err, err)
+		}
+		if runtimeErr.Line != 3 || runtimeErr.Msg != tt.expected {
+			t.Fatalf("tests[%d] - error wrong. expected=%q at line 3, got=%v", i, tt.expected, runtimeErr)
+		}
+	}
+}
+
+func TestRunUnaryMinus(t *testing.T) {
+	input := `
+		program test: var a, b: int; f: float;
+		func twice(v: int): int { return v * 2; }
+		{
+			a = 7;
+			b = 3;
+			print(a - -b, -a * b, -(a + b), -twice(b) + 1);
+			f = 2.5;
+			print(-f - -1, - -f, -(f * 2));
+			print(-9223372036854775808);
+		}
+	`
+	expectOutput(t, input, "10 -21 -10 -5\n-1.5 2.5 -5\n-9223372036854775808\n")
+}
+
+func TestRunAssociativity(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"8 / 4 / 2", "1"},
+		{"8 - 4 - 2", "2"},
+		{"100 / 10 * 2", "20"},
+		{"2 * 3 / 4 % 5", "1"},
+		{"100 % 7 * 2", "4"},
+		{"1 - 2 + 3", "2"},
+		{"2 + 3 * 4 - 5", "9"},
+		{"(2 + 3) * (4 - 5)", "-5"},
+		{"-2 * -3 - -4", "10"},
+		{"256 >> 2 >> 1", "32"},
+		{"1 << 2 << 3", "32"},
+		{"9.0 / 3 / 2", "1.5"},
+		{"7 - 2 * 3 > 1 - 1", "true"},
+		{"12 / 2 / 3 == 2", "true"},
+	}
+
+	for i, tt := range tests {
+		input := "program test: { print(" + tt.expr + "); }"
+		out, err := run(t, input)
+		if err != nil {
+			t.Fatalf("tests[%d] - %s: %s", i, tt.expr, err)
+		}
+		if out != tt.expected+"\n" {
+			t.Fatalf("tests[%d] - %s wrong. expected=%q, got=%q", i, tt.expr, tt.expected, out)
+		}
+	}
+}
+
+func TestRunStrings(t *testing.T) {
+	input := `
+		program test: var msg, cmds[2]: string; n: int; t: float;
+		func reply(cmd: string): string {
+			if (cmd == "ping") {
+				return "pong";
+			};
+			return "unknown " + cmd;
+		}
+		{
+			n = 7;
+			t = 21.5;
+			msg = "id=" + n + ";t=" + t;
+			print(msg, len(msg));
+			cmds[0] = "ping";
+			cmds[1] = "reboot";
+			print(reply(cmds[0]), reply(cmds[1]));
+			print(msg == "id=7;t=21.5", cmds[0] != "ping", len(""), 1 + 2 + "x" + 1 + 2);
+		}
+	`
+	expectOutput(t, input, "id=7;t=21.5 11\npong unknown reboot\ntrue false 0 3x12\n")
+}
+
+func TestRunStringEscapes(t *testing.T) {
+	input := `
+		program test: var msg: string; {
+			msg = "say \"hi\"\t\x41\u{e9}\\";
+			print(msg, len(msg));
+			print(` + "`raw \\n`" + `, "a" == ` + "`a`" + `);
+			print("two
+lines");
+		}
+	`
+	expectOutput(t, input, "say \"hi\"\tAé\\ 13\nraw \\n true\ntwo\nlines\n")
+}
+
+func TestRunNumericLiterals(t *testing.T) {
+	input := `
+		program test: var reg: int; {
+			reg = 0xA5;
+			print(reg, 0b1111_0000, 1_000_000, 0x7fff_ffff_ffff_ffff);
+			print((reg >> 4) & 0xF, reg & 0b1111, 010);
+			print(1.5e-3, 2E3, 1_000.25, -0x10);
+		}
+	`
+	expectOutput(t, input, "165 240 1000000 9223372036854775807\n10 5 10\n0.0015 2000 1000.25 -16\n")
+}
+
+func TestRunInitializedVars(t *testing.T) {
+	input := `
+		program test: var base = 0x10; ratio: float = 3; var label = "r";
+		var count = next();
+		func next(): int {
+			var calls = 0;
+			calls = calls + 1;
+			return calls + base;
+		}
+		{
+			print(base, ratio / 2, label + count, next());
+		}
+	`
+	expectOutput(t, input, "16 1.5 r17 17\n")
+}
+
+func TestRunBlockVars(t *testing.T) {
+	input := `
+		program test: var x = 1; i: int;
+		func count(n: int): int {
+			var total = 0;
+			while (n > 0) {
+				var part: int;
+				part = part + n;
+				total = total + part;
+				n = n - 1;
+			};
+			return total;
+		}
+		{
+			if (x > 0) {
+				var x = "inner";
+				print(x);
+			};
+			print(x);
+			for i = 1 to 3 {
+				var seen: int;
+				var label = "i" + i;
+				seen = seen + i;
+				print(label, seen);
+			};
+			print(count(3));
+		}
+	`
+	expectOutput(t, input, "inner\n1\ni1 1\ni2 2\ni3 3\n6\n")
+}